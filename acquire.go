@@ -0,0 +1,230 @@
+package isbclient
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// signalNotify, signalStop and resignal are replaced in tests.
+var (
+	signalNotify = defaultSignalNotify
+	signalStop   = defaultSignalStop
+	resignal     = defaultResignal
+)
+
+func defaultSignalNotify(c chan<- os.Signal, sig ...os.Signal) { signal.Notify(c, sig...) }
+
+func defaultSignalStop(c chan<- os.Signal) { signal.Stop(c) }
+
+// defaultResignal delivers sig to the current process again.
+func defaultResignal(sig os.Signal) {
+	if p, err := os.FindProcess(os.Getpid()); err == nil {
+		_ = p.Signal(sig)
+	}
+}
+
+// AcquireLeaseOptions configures AcquireLease. Zero fields take the documented defaults.
+type AcquireLeaseOptions struct {
+	Comments string
+
+	// PollInterval is the delay between checks while the lease awaits approval (default 10s).
+	PollInterval time.Duration
+
+	// ReuseActive returns an Active lease of UserEmail for the same template, if there is one,
+	// instead of requesting another. Reused leases are not terminated by Release.
+	ReuseActive bool
+	// UserEmail identifies the user whose leases may be reused. It defaults to the email in
+	// the claims of Client.Token.
+	UserEmail string
+
+	// HandleSignals releases the lease when the process receives SIGINT or SIGTERM, then
+	// delivers the signal again so the process exits as it would have.
+	HandleSignals bool
+
+	// ReleaseTimeout bounds the termination request made when the context is cancelled or a
+	// signal arrives (default 30s).
+	ReleaseTimeout time.Duration
+}
+
+// LeaseHandle is an Active lease returned by AcquireLease.
+type LeaseHandle struct {
+	Lease     Lease
+	LeaseID   string // ID for GetLeaseByID and TerminateLease
+	AccountID string // AWS account ID of the sandbox
+	Reused    bool   // the lease existed before AcquireLease
+
+	client  *Client
+	timeout time.Duration
+	once    sync.Once
+	err     error
+	done    chan struct{}
+}
+
+// Release terminates the lease, unless it was reused, and stops watching the context and
+// signals. It is safe to call more than once and from several goroutines; later calls return
+// the result of the first.
+func (h *LeaseHandle) Release(ctx context.Context) error {
+	h.once.Do(func() {
+		close(h.done)
+		if h.Reused {
+			return
+		}
+		err := h.client.TerminateLease(ctx, &TerminateLeaseRequest{LeaseID: h.LeaseID})
+		var conflict *LeaseConflictError
+		if errors.As(err, &conflict) {
+			// Already terminated or expired.
+			err = nil
+		}
+		h.err = err
+	})
+	return h.err
+}
+
+// releaseDetached releases the lease with a fresh context bounded by the handle's timeout.
+func (h *LeaseHandle) releaseDetached() {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	_ = h.Release(ctx)
+}
+
+// AcquireLease requests a lease of the template named or identified by templateNameOrUUID,
+// resolved as by ResolveLeaseTemplateUUID, waits until it has been approved and is Active with
+// an AWS account, and returns a handle whose Release terminates it. Release is also called when
+// ctx is cancelled, including while waiting for approval, and on SIGINT or SIGTERM with
+// HandleSignals. A lease that is denied or ends before becoming Active fails with a
+// *LeaseNotActiveError.
+func (c *Client) AcquireLease(ctx context.Context, templateNameOrUUID string, opts AcquireLeaseOptions) (*LeaseHandle, error) {
+	if templateNameOrUUID == "" {
+		return nil, &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("template is required")}
+	}
+	opts.PollInterval = durationOr(opts.PollInterval, 10*time.Second)
+	opts.ReleaseTimeout = durationOr(opts.ReleaseTimeout, 30*time.Second)
+
+	templateUUID, err := c.ResolveLeaseTemplateUUID(ctx, templateNameOrUUID)
+	if err != nil {
+		return nil, err
+	}
+	h := &LeaseHandle{client: c, timeout: opts.ReleaseTimeout, done: make(chan struct{})}
+
+	if opts.ReuseActive {
+		lease, ok, err := c.findActiveLease(ctx, templateUUID, opts.UserEmail)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			h.Lease, h.LeaseID, h.AccountID, h.Reused = lease, leaseIDOf(lease), lease.AwsAccountId, true
+			return h, nil
+		}
+	}
+
+	created, err := c.CreateLease(ctx, &CreateLeaseRequest{LeaseTemplateUUID: templateUUID, Comments: opts.Comments})
+	if err != nil {
+		return nil, err
+	}
+	h.Lease, h.LeaseID = created.Lease, leaseIDOf(created.Lease)
+	h.watch(ctx, opts.HandleSignals)
+
+	lease := created.Lease
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+	for !(lease.Status == LeaseStatusActive && lease.AwsAccountId != "") {
+		switch lease.Status {
+		case "", LeaseStatusPendingApproval, LeaseStatusActive:
+		default:
+			h.releaseDetached()
+			return nil, &LeaseNotActiveError{LeaseID: h.LeaseID, Status: lease.Status}
+		}
+		select {
+		case <-ctx.Done():
+			h.releaseDetached()
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		resp, err := c.GetLeaseByID(ctx, &GetLeaseByIDRequest{LeaseID: h.LeaseID})
+		if err != nil {
+			if ctx.Err() != nil {
+				h.releaseDetached()
+				return nil, ctx.Err()
+			}
+			// Polls are idempotent; ride out transient failures until the next tick.
+			if IsRetryable(&Call{Method: http.MethodGet}, err) {
+				continue
+			}
+			h.releaseDetached()
+			return nil, err
+		}
+		lease = resp.Lease
+	}
+	h.Lease, h.AccountID = lease, lease.AwsAccountId
+	return h, nil
+}
+
+// watch releases the lease when ctx is done or, with signals, on SIGINT or SIGTERM.
+func (h *LeaseHandle) watch(ctx context.Context, signals bool) {
+	var sigs chan os.Signal
+	if signals {
+		sigs = make(chan os.Signal, 1)
+		signalNotify(sigs, os.Interrupt, syscall.SIGTERM)
+	}
+	go func() {
+		var received os.Signal
+		defer func() {
+			if sigs != nil {
+				// Stop before delivering the signal again, so that it is not caught here.
+				signalStop(sigs)
+			}
+			if received != nil {
+				resignal(received)
+			}
+		}()
+		select {
+		case <-h.done:
+		case <-ctx.Done():
+			h.releaseDetached()
+		case received = <-sigs:
+			h.releaseDetached()
+		}
+	}()
+}
+
+// findActiveLease returns an Active lease of userEmail for templateUUID with an account.
+func (c *Client) findActiveLease(ctx context.Context, templateUUID, userEmail string) (Lease, bool, error) {
+	if userEmail == "" {
+		if claims, err := ParseJWT(c.Token); err == nil {
+			userEmail = claims.User.Email
+		}
+	}
+	if userEmail == "" {
+		return Lease{}, false, &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("UserEmail is required to reuse leases")}
+	}
+	resp, err := c.FetchAllLeases(ctx, &GetLeasesRequest{UserEmail: userEmail})
+	if err != nil {
+		return Lease{}, false, err
+	}
+	for _, lease := range resp.Leases {
+		if lease.UserEmail == userEmail && lease.OriginalLeaseTemplateUuid == templateUUID &&
+			lease.Status == LeaseStatusActive && lease.AwsAccountId != "" {
+			return lease, true, nil
+		}
+	}
+	return Lease{}, false, nil
+}
+
+// leaseIDOf returns the ID of lease, deriving it from the user email and UUID when the API
+// did not return one.
+func leaseIDOf(lease Lease) string {
+	if lease.LeaseId != "" {
+		return lease.LeaseId
+	}
+	b, _ := json.Marshal(map[string]string{"userEmail": lease.UserEmail, "uuid": lease.UUID})
+	return b64.StdEncoding.EncodeToString(b)
+}
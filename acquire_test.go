@@ -0,0 +1,207 @@
+package isbclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const acquireTemplateUUID = "6f1c2a4e-3b5d-4e7f-8a9b-0c1d2e3f4a5b"
+
+// leaseServer serves a template named Sandbox and a lease that is PendingApproval for the
+// first pendingPolls polls, then has the status final.
+type leaseServer struct {
+	*httptest.Server
+	pendingPolls int32
+	final        string
+	active       []Lease // returned by GET /leases
+
+	polls, creates, terminates atomic.Int32
+}
+
+func newLeaseServer(t *testing.T, pendingPolls int32, final string) *leaseServer {
+	t.Helper()
+	s := &leaseServer{pendingPolls: pendingPolls, final: final}
+	lease := Lease{UUID: "lease-uuid", UserEmail: "user@example.com", OriginalLeaseTemplateUuid: acquireTemplateUUID}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var data any
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/leaseTemplates":
+			data = map[string]any{"result": []LeaseTemplate{{UUID: acquireTemplateUUID, Name: "Sandbox"}}, "nextPageIdentifier": ""}
+		case r.Method == http.MethodGet && r.URL.Path == "/leases":
+			data = map[string]any{"result": s.active, "nextPageIdentifier": ""}
+		case r.Method == http.MethodPost && r.URL.Path == "/leases":
+			s.creates.Add(1)
+			created := lease
+			created.Status = LeaseStatusPendingApproval
+			data = created
+		case r.Method == http.MethodGet && r.URL.Path == "/leases/"+leaseIDOf(lease):
+			polled := lease
+			polled.Status = LeaseStatusPendingApproval
+			if s.polls.Add(1) > s.pendingPolls {
+				polled.Status = s.final
+				if s.final == LeaseStatusActive {
+					polled.AwsAccountId = "123456789012"
+				}
+			}
+			data = polled
+		case r.Method == http.MethodPost && r.URL.Path == "/leases/"+leaseIDOf(lease)+"/terminate":
+			s.terminates.Add(1)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": data})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestAcquireLease(t *testing.T) {
+	server := newLeaseServer(t, 2, LeaseStatusActive)
+	client := NewClient(server.URL, "token")
+
+	h, err := client.AcquireLease(context.Background(), "Sandbox", AcquireLeaseOptions{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("AcquireLease error: %v", err)
+	}
+	if h.AccountID != "123456789012" || h.Lease.Status != LeaseStatusActive || h.Reused {
+		t.Errorf("unexpected handle: %+v", h)
+	}
+	if h.LeaseID != leaseIDOf(Lease{UUID: "lease-uuid", UserEmail: "user@example.com"}) {
+		t.Errorf("unexpected lease ID %q", h.LeaseID)
+	}
+	if server.polls.Load() != 3 {
+		t.Errorf("expected 3 polls, got %d", server.polls.Load())
+	}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := h.Release(context.Background()); err != nil {
+				t.Errorf("Release error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if server.terminates.Load() != 1 {
+		t.Errorf("expected one termination, got %d", server.terminates.Load())
+	}
+}
+
+func TestAcquireLease_NotActive(t *testing.T) {
+	server := newLeaseServer(t, 0, "ApprovalDenied")
+	client := NewClient(server.URL, "token")
+
+	_, err := client.AcquireLease(context.Background(), acquireTemplateUUID, AcquireLeaseOptions{PollInterval: time.Millisecond})
+	var notActive *LeaseNotActiveError
+	if !errors.As(err, &notActive) || notActive.Status != "ApprovalDenied" {
+		t.Fatalf("expected LeaseNotActiveError, got %v", err)
+	}
+	if server.terminates.Load() != 1 {
+		t.Errorf("expected the lease to be terminated, got %d terminations", server.terminates.Load())
+	}
+}
+
+func TestAcquireLease_CancelledWhileWaiting(t *testing.T) {
+	server := newLeaseServer(t, 1<<30, LeaseStatusActive)
+	client := NewClient(server.URL, "token")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.AcquireLease(ctx, "Sandbox", AcquireLeaseOptions{PollInterval: time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if server.terminates.Load() != 1 {
+		t.Errorf("expected the pending lease to be terminated, got %d terminations", server.terminates.Load())
+	}
+}
+
+func TestAcquireLease_ReleasedOnSignal(t *testing.T) {
+	var notified chan<- os.Signal
+	var stops atomic.Int32
+	resignalled := make(chan os.Signal, 1)
+	signalNotify = func(c chan<- os.Signal, _ ...os.Signal) { notified = c }
+	signalStop = func(c chan<- os.Signal) {
+		if c != notified {
+			t.Error("expected the notified channel to be stopped")
+		}
+		stops.Add(1)
+	}
+	resignal = func(sig os.Signal) {
+		if stops.Load() != 1 {
+			t.Errorf("expected signals to be stopped once before delivering again, got %d stops", stops.Load())
+		}
+		resignalled <- sig
+	}
+	t.Cleanup(func() {
+		signalNotify = defaultSignalNotify
+		signalStop = defaultSignalStop
+		resignal = defaultResignal
+	})
+
+	server := newLeaseServer(t, 0, LeaseStatusActive)
+	client := NewClient(server.URL, "token")
+	if _, err := client.AcquireLease(context.Background(), "Sandbox", AcquireLeaseOptions{PollInterval: time.Millisecond, HandleSignals: true}); err != nil {
+		t.Fatalf("AcquireLease error: %v", err)
+	}
+	notified <- os.Interrupt
+	select {
+	case sig := <-resignalled:
+		if sig != os.Interrupt {
+			t.Errorf("expected the interrupt to be delivered again, got %v", sig)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the signal to be delivered again")
+	}
+	if server.terminates.Load() != 1 {
+		t.Errorf("expected the lease to be terminated, got %d terminations", server.terminates.Load())
+	}
+}
+
+func TestAcquireLease_ReuseActive(t *testing.T) {
+	server := newLeaseServer(t, 0, LeaseStatusActive)
+	server.active = []Lease{
+		{UUID: "other", UserEmail: "user@example.com", OriginalLeaseTemplateUuid: "another-template", Status: LeaseStatusActive, AwsAccountId: "111111111111"},
+		{UUID: "existing", UserEmail: "user@example.com", OriginalLeaseTemplateUuid: acquireTemplateUUID, Status: LeaseStatusActive, AwsAccountId: "222222222222"},
+	}
+	client := NewClient(server.URL, testToken(t, "user@example.com", time.Hour))
+
+	h, err := client.AcquireLease(context.Background(), "Sandbox", AcquireLeaseOptions{ReuseActive: true})
+	if err != nil {
+		t.Fatalf("AcquireLease error: %v", err)
+	}
+	if !h.Reused || h.AccountID != "222222222222" || server.creates.Load() != 0 {
+		t.Errorf("expected the existing lease to be reused, got %+v", h)
+	}
+	if err := h.Release(context.Background()); err != nil || server.terminates.Load() != 0 {
+		t.Errorf("expected a reused lease not to be terminated, got %v", err)
+	}
+}
+
+func TestAcquireLease_UnknownTemplate(t *testing.T) {
+	server := newLeaseServer(t, 0, LeaseStatusActive)
+	client := NewClient(server.URL, "token")
+
+	_, err := client.AcquireLease(context.Background(), "Missing", AcquireLeaseOptions{})
+	var notFound *LeaseTemplateNotFoundError
+	if !errors.As(err, &notFound) || !strings.Contains(err.Error(), "Missing") {
+		t.Errorf("expected LeaseTemplateNotFoundError, got %v", err)
+	}
+	if server.creates.Load() != 0 {
+		t.Error("expected no lease to be requested")
+	}
+}
@@ -1,6 +1,14 @@
 package isbclient
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -12,6 +20,31 @@ type UserClaims struct {
 	UserName    string   `json:"userName"`
 	Email       string   `json:"email"`
 	Roles       []string `json:"roles"`
+
+	custom map[string]json.RawMessage
+}
+
+// CustomFields returns a copy of the members of the user claims beyond the four above, such as
+// those set with UserClaimsBuilder.Field.
+func (u UserClaims) CustomFields() map[string]json.RawMessage {
+	return maps.Clone(u.custom)
+}
+
+// UnmarshalJSON decodes UserClaims and retains custom members.
+func (u *UserClaims) UnmarshalJSON(data []byte) error {
+	type plain UserClaims
+	custom, err := unmarshalWithUnknown(data, (*plain)(u))
+	if err != nil {
+		return err
+	}
+	u.custom = custom
+	return nil
+}
+
+// MarshalJSON encodes UserClaims including any custom members.
+func (u UserClaims) MarshalJSON() ([]byte, error) {
+	type plain UserClaims
+	return marshalWithUnknown(plain(u), u.custom)
 }
 
 // Claims is the JWT claims structure for the API.
@@ -27,18 +60,18 @@ const (
 	RoleUser    = "User"
 )
 
-// GenerateJWT generates a JWT token string with the given user claims, secret, and expiry duration.
+// GenerateJWT generates an HS256 JWT token string with the given user claims, secret, and
+// expiry duration. The token always carries exp, so one generated with a zero expiresIn has
+// already expired. Use SignToken for other algorithms, a kid header or further claims.
 func GenerateJWT(user UserClaims, secret string, expiresIn time.Duration) (string, error) {
 	now := time.Now()
-	claims := Claims{
-		User: user,
-		RegisteredClaims: jwt.RegisteredClaims{
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
-		},
+	claims, err := tokenClaims(user, TokenOptions{}, now)
+	if err != nil {
+		return "", err
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(expiresIn))
+	signer := &keySigner{method: jwt.SigningMethodHS256, key: []byte(secret)}
+	return signer.Sign(claims)
 }
 
 // NewAdminUserClaims returns a UserClaims struct for an admin user with the given email.
@@ -60,3 +93,79 @@ func NewUserUserClaims(email string) UserClaims {
 		Roles:       []string{RoleUser},
 	}
 }
+
+// ParseJWT decodes the claims of tokenString without verifying its signature or expiry. Use it
+// to inspect tokens the caller already trusts, such as its own; use VerifyJWT for tokens
+// received from others.
+func ParseJWT(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return nil, &InvalidTokenError{Err: err}
+	}
+	return claims, nil
+}
+
+// VerifyOptions configures VerifyJWT. Exactly one of Secret and Key must be set.
+type VerifyOptions struct {
+	// Secret verifies HS256, HS384 and HS512 tokens.
+	Secret string
+	// Key verifies RS256 tokens when an *rsa.PublicKey and ES256 tokens when an *ecdsa.PublicKey.
+	Key crypto.PublicKey
+
+	// Leeway allows for clock skew when checking exp, nbf and iat.
+	Leeway time.Duration
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// Roles, when set, requires the token to carry at least one of the roles.
+	Roles []string
+}
+
+// VerifyJWT verifies the signature and registered claims of tokenString and returns its
+// claims. It returns a *TokenExpiredError for an expired token, a *TokenSignatureError for a
+// signature that does not verify, a *MissingRolesError when opts.Roles are not met and an
+// *InvalidTokenError otherwise, including for a token without an exp claim.
+func VerifyJWT(tokenString string, opts VerifyOptions) (*Claims, error) {
+	if opts.Key != nil && opts.Secret != "" {
+		return nil, &InvalidTokenError{Err: errors.New("both a secret and a key were given")}
+	}
+	var key any
+	var methods []string
+	switch k := opts.Key.(type) {
+	case nil:
+		if opts.Secret == "" {
+			return nil, &InvalidTokenError{Err: errors.New("no secret or key to verify with")}
+		}
+		key, methods = []byte(opts.Secret), []string{"HS256", "HS384", "HS512"}
+	case *rsa.PublicKey:
+		key, methods = k, []string{"RS256"}
+	case *ecdsa.PublicKey:
+		key, methods = k, []string{"ES256"}
+	default:
+		return nil, &InvalidTokenError{Err: fmt.Errorf("unsupported verification key type %T", opts.Key)}
+	}
+
+	parserOpts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithLeeway(opts.Leeway), jwt.WithIssuedAt(), jwt.WithExpirationRequired()}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	claims := &Claims{}
+	_, err := jwt.NewParser(parserOpts...).ParseWithClaims(tokenString, claims, func(*jwt.Token) (any, error) {
+		return key, nil
+	})
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return nil, &TokenExpiredError{ExpiredAt: claims.ExpiresAt.Time}
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return nil, &TokenSignatureError{Err: err}
+	case err != nil:
+		return nil, &InvalidTokenError{Err: err}
+	}
+	if len(opts.Roles) > 0 && !slices.ContainsFunc(claims.User.Roles, func(r string) bool { return slices.Contains(opts.Roles, r) }) {
+		return nil, &MissingRolesError{Required: opts.Roles, Roles: claims.User.Roles}
+	}
+	return claims, nil
+}
@@ -1,6 +1,10 @@
 package isbclient
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -62,3 +66,96 @@ func TestGenerateJWT(t *testing.T) {
 		t.Errorf("expected Roles ['%s'], got %v", RoleAdmin, claims.User.Roles)
 	}
 }
+
+func TestGenerateJWT_ZeroExpiry(t *testing.T) {
+	tokenStr, err := GenerateJWT(NewAdminUserClaims("admin@example.com"), "secret", 0)
+	if err != nil {
+		t.Fatalf("GenerateJWT error: %v", err)
+	}
+	claims, err := ParseJWT(tokenStr)
+	if err != nil || claims.ExpiresAt == nil || claims.ExpiresAt.After(time.Now()) {
+		t.Fatalf("expected a token expiring when issued, got %+v %v", claims, err)
+	}
+	var expired *TokenExpiredError
+	if _, err := VerifyJWT(tokenStr, VerifyOptions{Secret: "secret"}); !errors.As(err, &expired) {
+		t.Errorf("expected TokenExpiredError, got %T %v", err, err)
+	}
+}
+
+func TestParseJWT(t *testing.T) {
+	tokenStr, _ := GenerateJWT(NewUserUserClaims("user@example.com"), "secret", -time.Hour)
+	claims, err := ParseJWT(tokenStr)
+	if err != nil {
+		t.Fatalf("ParseJWT error: %v", err)
+	}
+	if claims.User.Email != "user@example.com" {
+		t.Errorf("expected the claims of an expired token to be decoded, got %+v", claims.User)
+	}
+	var invalid *InvalidTokenError
+	if _, err := ParseJWT("not-a-token"); !errors.As(err, &invalid) {
+		t.Errorf("expected InvalidTokenError, got %T %v", err, err)
+	}
+}
+
+func TestVerifyJWT(t *testing.T) {
+	admin, _ := GenerateJWT(NewAdminUserClaims("admin@example.com"), "secret", time.Hour)
+	user, _ := GenerateJWT(NewUserUserClaims("user@example.com"), "secret", time.Hour)
+	expired, _ := GenerateJWT(NewAdminUserClaims("admin@example.com"), "secret", -30*time.Second)
+
+	claims, err := VerifyJWT(admin, VerifyOptions{Secret: "secret", Roles: []string{RoleAdmin, RoleManager}})
+	if err != nil {
+		t.Fatalf("VerifyJWT error: %v", err)
+	}
+	if claims.User.Email != "admin@example.com" {
+		t.Errorf("unexpected claims: %+v", claims.User)
+	}
+	if _, err := VerifyJWT(expired, VerifyOptions{Secret: "secret", Leeway: time.Minute}); err != nil {
+		t.Errorf("expected the leeway to accept a recently expired token, got %v", err)
+	}
+
+	var expiredErr *TokenExpiredError
+	if _, err := VerifyJWT(expired, VerifyOptions{Secret: "secret"}); !errors.As(err, &expiredErr) || expiredErr.ExpiredAt.IsZero() {
+		t.Errorf("expected TokenExpiredError, got %T %v", err, err)
+	}
+	var signature *TokenSignatureError
+	if _, err := VerifyJWT(admin, VerifyOptions{Secret: "other"}); !errors.As(err, &signature) {
+		t.Errorf("expected TokenSignatureError, got %T %v", err, err)
+	}
+	var missing *MissingRolesError
+	if _, err := VerifyJWT(user, VerifyOptions{Secret: "secret", Roles: []string{RoleAdmin}}); !errors.As(err, &missing) || missing.Roles[0] != RoleUser {
+		t.Errorf("expected MissingRolesError, got %T %v", err, err)
+	}
+	var invalid *InvalidTokenError
+	if _, err := VerifyJWT(admin, VerifyOptions{}); !errors.As(err, &invalid) {
+		t.Errorf("expected InvalidTokenError without a secret, got %T %v", err, err)
+	}
+	noExp, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user": map[string]any{"email": "admin@example.com", "roles": []string{RoleAdmin}},
+	}).SignedString([]byte("secret"))
+	if _, err := VerifyJWT(noExp, VerifyOptions{Secret: "secret"}); !errors.As(err, &invalid) {
+		t.Errorf("expected InvalidTokenError for a token without exp, got %T %v", err, err)
+	}
+}
+
+func TestVerifyJWT_Key(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, _ := NewECDSASigner(key, "ec")
+	tokenStr, _ := SignToken(signer, NewAdminUserClaims("admin@example.com"), TokenOptions{ExpiresIn: time.Hour, Issuer: "isb", Audience: []string{"svc"}})
+
+	if _, err := VerifyJWT(tokenStr, VerifyOptions{Key: &key.PublicKey, Issuer: "isb", Audience: "svc"}); err != nil {
+		t.Errorf("VerifyJWT error: %v", err)
+	}
+	var invalid *InvalidTokenError
+	if _, err := VerifyJWT(tokenStr, VerifyOptions{Key: &key.PublicKey, Audience: "other"}); !errors.As(err, &invalid) {
+		t.Errorf("expected InvalidTokenError for another audience, got %T %v", err, err)
+	}
+	// An HS256 token must not verify against a public key.
+	hmac, _ := GenerateJWT(NewAdminUserClaims("admin@example.com"), "secret", time.Hour)
+	var signature *TokenSignatureError
+	if _, err := VerifyJWT(hmac, VerifyOptions{Key: &key.PublicKey}); !errors.As(err, &signature) {
+		t.Errorf("expected TokenSignatureError for an unexpected algorithm, got %T %v", err, err)
+	}
+}
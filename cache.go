@@ -0,0 +1,303 @@
+package isbclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheResource identifies a kind of data held by a Cache.
+type CacheResource int
+
+const (
+	CacheConfiguration  CacheResource = iota // GetConfigurations
+	CacheLeaseTemplates                      // GetLeaseTemplates, GetLeaseTemplateByID, FetchAllLeaseTemplates
+	CacheAccounts                            // GetAccounts, FetchAllAccounts
+	numCacheResources
+)
+
+// cachedOperations maps the operations a Cache serves to the resource they read.
+var cachedOperations = map[string]CacheResource{
+	"GetConfigurations":      CacheConfiguration,
+	"GetLeaseTemplates":      CacheLeaseTemplates,
+	"GetLeaseTemplateByID":   CacheLeaseTemplates,
+	"FetchAllLeaseTemplates": CacheLeaseTemplates,
+	"GetAccounts":            CacheAccounts,
+	"FetchAllAccounts":       CacheAccounts,
+}
+
+// invalidatingOperations maps the operations that modify a resource to that resource.
+var invalidatingOperations = map[string]CacheResource{
+	"CreateLeaseTemplate": CacheLeaseTemplates,
+	"UpdateLeaseTemplate": CacheLeaseTemplates,
+	"PatchLeaseTemplate":  CacheLeaseTemplates,
+	"DeleteLeaseTemplate": CacheLeaseTemplates,
+	"RegisterAccount":     CacheAccounts,
+	"EjectAccount":        CacheAccounts,
+	"RetryCleanup":        CacheAccounts,
+}
+
+// resourcePaths maps path prefixes to resources, for invalidating after mutations made with Do.
+var resourcePaths = []struct {
+	prefix   string
+	resource CacheResource
+}{
+	{"/configurations", CacheConfiguration},
+	{"/leaseTemplates", CacheLeaseTemplates},
+	{"/accounts", CacheAccounts},
+}
+
+// CacheOptions configures a Cache. Zero TTLs take the documented defaults; a negative TTL
+// disables caching of that resource.
+type CacheOptions struct {
+	ConfigurationTTL  time.Duration // default 5m
+	LeaseTemplatesTTL time.Duration // default 1m
+	AccountsTTL       time.Duration // default 30s
+
+	// StaleWhileRevalidate is how long past its TTL an entry may still be served while it is
+	// refreshed in the background. Zero disables stale serving.
+	StaleWhileRevalidate time.Duration
+}
+
+// Cache is a read-through cache for global configuration, lease templates and accounts,
+// installed on a client with Use:
+//
+//	cache := isbclient.NewCache(isbclient.CacheOptions{})
+//	client.Use(cache.Middleware())
+//
+// Identical concurrent reads are coalesced into a single request. Errors are not cached.
+// Mutations made through the same client (UpdateLeaseTemplate, PatchLeaseTemplate,
+// DeleteLeaseTemplate, RegisterAccount, EjectAccount, RetryCleanup, and Do calls other than
+// GET) invalidate the affected resource.
+//
+// Cached responses are shared between callers and must not be modified. A read that coalesced
+// with one whose context was cancelled is fetched again rather than failing with that
+// context's error.
+type Cache struct {
+	ttl [numCacheResources]time.Duration
+	swr time.Duration
+	now func() time.Time
+
+	mu         sync.Mutex
+	entries    map[string]*cacheEntry
+	flights    map[string]*cacheFlight
+	generation [numCacheResources]uint64
+}
+
+type cacheEntry struct {
+	resource   CacheResource
+	value      any
+	expires    time.Time
+	staleUntil time.Time
+	refreshing bool
+}
+
+// cacheFlight is a fetch in progress that identical reads wait for.
+type cacheFlight struct {
+	done  chan struct{}
+	value any
+	err   error
+	// abandoned is set when the fetch failed because the context of the read that started
+	// it was done; waiters with a live context fetch again instead of taking its error.
+	abandoned bool
+}
+
+// NewCache returns an empty Cache configured by opts.
+func NewCache(opts CacheOptions) *Cache {
+	c := &Cache{
+		swr:     opts.StaleWhileRevalidate,
+		now:     time.Now,
+		entries: map[string]*cacheEntry{},
+		flights: map[string]*cacheFlight{},
+	}
+	c.ttl[CacheConfiguration] = durationOr(opts.ConfigurationTTL, 5*time.Minute)
+	c.ttl[CacheLeaseTemplates] = durationOr(opts.LeaseTemplatesTTL, time.Minute)
+	c.ttl[CacheAccounts] = durationOr(opts.AccountsTTL, 30*time.Second)
+	return c
+}
+
+func durationOr(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
+
+// Invalidate drops cached entries of the given resources, or of all resources if none are
+// given. Reads in flight when Invalidate is called are not cached.
+func (c *Cache) Invalidate(resources ...CacheResource) {
+	if len(resources) == 0 {
+		resources = []CacheResource{CacheConfiguration, CacheLeaseTemplates, CacheAccounts}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range resources {
+		c.generation[r]++
+		for key, e := range c.entries {
+			if e.resource == r {
+				delete(c.entries, key)
+			}
+		}
+	}
+}
+
+// Middleware returns the middleware serving reads from the cache. Register it before other
+// middleware so that cache hits skip them.
+func (c *Cache) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			if resource, ok := cachedOperations[call.Operation]; ok && c.ttl[resource] > 0 {
+				return c.get(ctx, call, resource, next)
+			}
+			res, err := next(ctx, call)
+			if resource, ok := invalidatedResource(call); ok {
+				c.Invalidate(resource)
+			}
+			return res, err
+		}
+	}
+}
+
+// invalidatedResource returns the resource modified by call, if any.
+func invalidatedResource(call *Call) (CacheResource, bool) {
+	if resource, ok := invalidatingOperations[call.Operation]; ok {
+		return resource, true
+	}
+	if call.Operation == "Do" && call.Method != http.MethodGet && call.Method != http.MethodHead {
+		for _, p := range resourcePaths {
+			if strings.HasPrefix(call.Path, p.prefix) {
+				return p.resource, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// get serves call from the cache, fetching it through next on a miss.
+func (c *Cache) get(ctx context.Context, call *Call, resource CacheResource, next Handler) (any, error) {
+	key := cacheKey(call)
+	now := c.now()
+
+	if bypassCache(ctx) {
+		// Skip both entries and fetches in flight, which may have started before the change
+		// the caller wants to observe.
+		c.mu.Lock()
+		generation := c.generation[resource]
+		c.mu.Unlock()
+		value, err := next(ctx, call)
+		if err == nil {
+			c.mu.Lock()
+			c.store(key, resource, generation, value)
+			c.mu.Unlock()
+		}
+		return value, err
+	}
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		if now.Before(e.expires) {
+			c.mu.Unlock()
+			return e.value, nil
+		}
+		if now.Before(e.staleUntil) {
+			if !e.refreshing {
+				e.refreshing = true
+				go c.refresh(ctx, call, key, resource, next)
+			}
+			c.mu.Unlock()
+			return e.value, nil
+		}
+		delete(c.entries, key)
+	}
+	if f, ok := c.flights[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-f.done:
+			if f.abandoned && ctx.Err() == nil {
+				return c.get(ctx, call, resource, next)
+			}
+			return f.value, f.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	f := &cacheFlight{done: make(chan struct{})}
+	c.flights[key] = f
+	generation := c.generation[resource]
+	c.mu.Unlock()
+
+	f.value, f.err = next(ctx, call)
+	f.abandoned = f.err != nil && ctx.Err() != nil
+
+	c.mu.Lock()
+	delete(c.flights, key)
+	if f.err == nil {
+		c.store(key, resource, generation, f.value)
+	}
+	c.mu.Unlock()
+	close(f.done)
+	return f.value, f.err
+}
+
+// refresh fetches a stale entry again in the background. It runs detached from the caller,
+// with its own copy of the call so that the caller's middleware is unaffected.
+func (c *Cache) refresh(ctx context.Context, call *Call, key string, resource CacheResource, next Handler) {
+	c.mu.Lock()
+	generation := c.generation[resource]
+	c.mu.Unlock()
+
+	bg := *call
+	bg.Header = call.Header.Clone()
+	bg.Response = nil
+	ctx = context.WithValue(context.WithoutCancel(ctx), responseMetadataKey{}, (*ResponseMetadata)(nil))
+	value, err := next(ctx, &bg)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		c.store(key, resource, generation, value)
+	} else if e, ok := c.entries[key]; ok {
+		e.refreshing = false
+	}
+}
+
+// store caches value unless resource was invalidated since generation. c.mu must be held.
+func (c *Cache) store(key string, resource CacheResource, generation uint64, value any) {
+	if c.generation[resource] != generation {
+		return
+	}
+	now := c.now()
+	expires := now.Add(c.ttl[resource])
+	c.entries[key] = &cacheEntry{
+		resource:   resource,
+		value:      value,
+		expires:    expires,
+		staleUntil: expires.Add(c.swr),
+	}
+}
+
+type cacheBypassKey struct{}
+
+// withoutCachedValue returns a context whose reads skip cached entries and fetches in flight;
+// their results are still cached.
+func withoutCachedValue(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func bypassCache(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// cacheKey identifies the data read by call.
+func cacheKey(call *Call) string {
+	if call.Method != "" {
+		return call.Operation + " " + call.Method + " " + call.Path + "?" + call.Query.Encode()
+	}
+	// Operations made of several requests are keyed by their request value.
+	req, _ := json.Marshal(call.Request)
+	return call.Operation + " " + string(req)
+}
@@ -0,0 +1,319 @@
+package isbclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for Cache tests.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newCachedClient returns a client with a Cache, its clock, and counts of GET requests by
+// path. Each GET response differs from the previous one.
+func newCachedClient(t *testing.T, opts CacheOptions) (*Client, *Cache, *fakeClock, map[string]*atomic.Int32) {
+	t.Helper()
+	hits := map[string]*atomic.Int32{"/configurations": {}, "/leaseTemplates": {}, "/accounts": {}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet {
+			_, _ = w.Write([]byte(`{"status":"success","data":{}}`))
+			return
+		}
+		n := hits[r.URL.Path].Add(1)
+		switch r.URL.Path {
+		case "/configurations":
+			fmt.Fprintf(w, `{"status":"success","data":{"termsOfService":"v%d"}}`, n)
+		case "/leaseTemplates":
+			fmt.Fprintf(w, `{"status":"success","data":{"result":[{"uuid":"tpl-%d"}]}}`, n)
+		default:
+			fmt.Fprintf(w, `{"status":"success","data":{"result":[{"awsAccountId":"%012d"}]}}`, n)
+		}
+	}))
+	t.Cleanup(server.Close)
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	cache := NewCache(opts)
+	cache.now = clock.Now
+	client := NewClient(server.URL, "token")
+	client.Use(cache.Middleware())
+	return client, cache, clock, hits
+}
+
+func TestCache_TTL(t *testing.T) {
+	client, _, clock, hits := newCachedClient(t, CacheOptions{ConfigurationTTL: time.Minute})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		cfg, err := client.GetConfigurations(ctx)
+		if err != nil {
+			t.Fatalf("GetConfigurations error: %v", err)
+		}
+		if cfg.TermsOfService != "v1" {
+			t.Errorf("expected cached configuration v1, got %q", cfg.TermsOfService)
+		}
+	}
+	if hits["/configurations"].Load() != 1 {
+		t.Errorf("expected 1 request, got %d", hits["/configurations"].Load())
+	}
+
+	clock.Advance(time.Minute)
+	cfg, err := client.GetConfigurations(ctx)
+	if err != nil {
+		t.Fatalf("GetConfigurations error: %v", err)
+	}
+	if cfg.TermsOfService != "v2" || hits["/configurations"].Load() != 2 {
+		t.Errorf("expected a fresh fetch after the TTL, got %q after %d requests", cfg.TermsOfService, hits["/configurations"].Load())
+	}
+}
+
+func TestCache_Coalescing(t *testing.T) {
+	release := make(chan struct{})
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"termsOfService":"v1"}}`))
+	}))
+	defer server.Close()
+	cache := NewCache(CacheOptions{})
+	client := NewClient(server.URL, "token")
+	client.Use(cache.Middleware())
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]*GlobalConfiguration, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = client.GetConfigurations(context.Background())
+		}()
+	}
+	// Give the callers time to join the flight before the response is released.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if hits.Load() != 1 {
+		t.Errorf("expected concurrent reads to be coalesced into 1 request, got %d", hits.Load())
+	}
+	for i, cfg := range results {
+		if cfg == nil || cfg.TermsOfService != "v1" {
+			t.Errorf("caller %d: unexpected result %+v", i, cfg)
+		}
+	}
+}
+
+func TestCache_InvalidatedByMutations(t *testing.T) {
+	client, _, _, hits := newCachedClient(t, CacheOptions{})
+	ctx := context.Background()
+
+	mutations := []struct {
+		name   string
+		path   string
+		mutate func() error
+	}{
+		{"CreateLeaseTemplate", "/leaseTemplates", func() error {
+			_, err := client.CreateLeaseTemplate(ctx, &CreateLeaseTemplateRequest{Name: "x"})
+			return err
+		}},
+		{"UpdateLeaseTemplate", "/leaseTemplates", func() error {
+			_, err := client.UpdateLeaseTemplate(ctx, &UpdateLeaseTemplateRequest{LeaseTemplateID: "tpl-1", Name: "x"})
+			return err
+		}},
+		{"DeleteLeaseTemplate", "/leaseTemplates", func() error {
+			return client.DeleteLeaseTemplate(ctx, &DeleteLeaseTemplateRequest{LeaseTemplateID: "tpl-1"})
+		}},
+		{"Do", "/leaseTemplates", func() error {
+			return client.Do(ctx, http.MethodPost, "/leaseTemplates", nil, map[string]string{"name": "x"}, nil)
+		}},
+		{"RegisterAccount", "/accounts", func() error {
+			_, err := client.RegisterAccount(ctx, &RegisterAccountRequest{AwsAccountId: "123456789012"})
+			return err
+		}},
+	}
+	read := func(path string) error {
+		if path == "/accounts" {
+			_, err := client.GetAccounts(ctx, nil)
+			return err
+		}
+		_, err := client.GetLeaseTemplates(ctx, nil)
+		return err
+	}
+	for _, m := range mutations {
+		t.Run(m.name, func(t *testing.T) {
+			if err := read(m.path); err != nil {
+				t.Fatalf("read error: %v", err)
+			}
+			before := hits[m.path].Load()
+			if err := read(m.path); err != nil {
+				t.Fatalf("read error: %v", err)
+			}
+			if hits[m.path].Load() != before {
+				t.Fatalf("expected the second read to be served from the cache")
+			}
+			if err := m.mutate(); err != nil {
+				t.Fatalf("mutation error: %v", err)
+			}
+			if err := read(m.path); err != nil {
+				t.Fatalf("read error: %v", err)
+			}
+			if hits[m.path].Load() != before+1 {
+				t.Errorf("expected the read after %s to reach the server", m.name)
+			}
+		})
+	}
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	client, cache, _, hits := newCachedClient(t, CacheOptions{})
+	ctx := context.Background()
+
+	_, _ = client.GetConfigurations(ctx)
+	_, _ = client.GetLeaseTemplates(ctx, nil)
+	cache.Invalidate(CacheConfiguration)
+	_, _ = client.GetConfigurations(ctx)
+	_, _ = client.GetLeaseTemplates(ctx, nil)
+	if hits["/configurations"].Load() != 2 || hits["/leaseTemplates"].Load() != 1 {
+		t.Errorf("expected only the configuration to be refetched, got %d and %d requests",
+			hits["/configurations"].Load(), hits["/leaseTemplates"].Load())
+	}
+
+	cache.Invalidate()
+	_, _ = client.GetLeaseTemplates(ctx, nil)
+	if hits["/leaseTemplates"].Load() != 2 {
+		t.Errorf("expected Invalidate() to drop every resource")
+	}
+}
+
+func TestCache_StaleWhileRevalidate(t *testing.T) {
+	client, _, clock, hits := newCachedClient(t, CacheOptions{ConfigurationTTL: time.Minute, StaleWhileRevalidate: time.Minute})
+	ctx := context.Background()
+
+	if _, err := client.GetConfigurations(ctx); err != nil {
+		t.Fatalf("GetConfigurations error: %v", err)
+	}
+	clock.Advance(90 * time.Second)
+	cfg, err := client.GetConfigurations(ctx)
+	if err != nil {
+		t.Fatalf("GetConfigurations error: %v", err)
+	}
+	if cfg.TermsOfService != "v1" {
+		t.Errorf("expected the stale entry to be served, got %q", cfg.TermsOfService)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		cfg, err = client.GetConfigurations(ctx)
+		if err != nil {
+			t.Fatalf("GetConfigurations error: %v", err)
+		}
+		if cfg.TermsOfService == "v2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the entry to be refreshed in the background")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if hits["/configurations"].Load() != 2 {
+		t.Errorf("expected a single background refresh, got %d requests", hits["/configurations"].Load())
+	}
+
+	clock.Advance(3 * time.Minute)
+	cfg, _ = client.GetConfigurations(ctx)
+	if cfg.TermsOfService != "v3" {
+		t.Errorf("expected a synchronous fetch past the stale window, got %q", cfg.TermsOfService)
+	}
+}
+
+func TestCache_ErrorsNotCached(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if hits.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"status":"error","message":"boom"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"termsOfService":"v1"}}`))
+	}))
+	defer server.Close()
+	cache := NewCache(CacheOptions{})
+	client := NewClient(server.URL, "token")
+	client.Use(cache.Middleware())
+
+	if _, err := client.GetConfigurations(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	cfg, err := client.GetConfigurations(context.Background())
+	if err != nil || cfg.TermsOfService != "v1" {
+		t.Errorf("expected the failed read not to be cached, got %+v, %v", cfg, err)
+	}
+}
+
+func TestCache_CoalescedReadSurvivesCancelledLeader(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			// The first read hangs until its caller gives up.
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"termsOfService":"v1"}}`))
+	}))
+	defer server.Close()
+	cache := NewCache(CacheOptions{})
+	client := NewClient(server.URL, "token")
+	client.Use(cache.Middleware())
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, err := client.GetConfigurations(leaderCtx)
+		leaderDone <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	waiterDone := make(chan *GlobalConfiguration, 1)
+	go func() {
+		cfg, err := client.GetConfigurations(context.Background())
+		if err != nil {
+			t.Errorf("expected the waiting read to succeed, got %v", err)
+		}
+		waiterDone <- cfg
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	if err := <-leaderDone; err == nil {
+		t.Error("expected the cancelled read to fail")
+	}
+	if cfg := <-waiterDone; cfg == nil || cfg.TermsOfService != "v1" {
+		t.Errorf("unexpected result %+v", cfg)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("expected the waiting read to fetch again, got %d requests", hits.Load())
+	}
+}
@@ -5,46 +5,45 @@ import (
 	"context"
 	b64 "encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
 
+//go:generate go run ./cmd/isbgen
+
 // Client is the HTTP client for the Innovation Sandbox API.
 // It supports bearer token authentication.
 type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
 	Token      string
-}
 
-// authTransport is a custom RoundTripper that injects the Authorization header.
-type authTransport struct {
-	base  http.RoundTripper
-	token string
-}
+	// MaxResponseSize caps the number of response body bytes read for a single call.
+	// Zero means DefaultMaxResponseSize; a negative value disables the limit.
+	MaxResponseSize int64
 
-// RoundTrip implements the http.RoundTripper interface.
-func (a *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	if a.token != "" {
-		req = req.Clone(req.Context())
-		req.Header.Set("Authorization", "Bearer "+a.token)
-	}
-	return a.base.RoundTrip(req)
+	// DefaultPageSize is sent as the page size of list requests that do not set one. Zero
+	// leaves the page size to the API.
+	DefaultPageSize int
+
+	// TemplateCacheTTL is how long lease templates fetched to resolve template names are
+	// reused. Zero means DefaultTemplateCacheTTL; a negative value disables the cache.
+	TemplateCacheTTL time.Duration
+
+	middleware []Middleware
+	templates  templateCache
 }
 
 // NewClient creates a new API client with recommended timeouts and settings.
 func NewClient(baseURL, token string) *Client {
-	baseTransport := http.DefaultTransport
 	httpClient := &http.Client{
 		Timeout: 15 * time.Second, // 15 seconds
-		Transport: &authTransport{
-			base:  baseTransport,
-			token: token,
-		},
 	}
 	return &Client{
 		BaseURL:    baseURL,
@@ -55,30 +54,14 @@ func NewClient(baseURL, token string) *Client {
 
 // GetLeases fetches a paginated list of leases and returns typed data
 func (c *Client) GetLeases(ctx context.Context, req QueryBuilder) (*GetLeasesResponse, error) {
-	u, err := url.Parse(c.BaseURL + "/leases")
-	if err != nil {
-		return nil, &APIRequestError{Op: "parse", URL: c.BaseURL + "/leases", Err: err}
-	}
-
-	if req != nil {
-		u.RawQuery = req.BuildQuery().Encode()
-	}
-
-	resp, err := c.doGet(ctx, u.String())
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var wrapper struct {
-		Status string            `json:"status"`
-		Data   GetLeasesResponse `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&wrapper); err != nil {
-		return nil, &JSONDecodingError{Err: err}
-	}
-
-	return &wrapper.Data, nil
+	call := apiCall{method: http.MethodGet, path: "/leases", query: c.listQuery(req)}
+	return invoke(ctx, c, "GetLeases", req, call, func(ctx context.Context) (*GetLeasesResponse, error) {
+		page, err := do[Page[Lease]](ctx, c, call)
+		if err != nil {
+			return nil, err
+		}
+		return &GetLeasesResponse{Leases: page.Result, NextPageIdentifier: page.NextPageIdentifier}, nil
+	})
 }
 
 // GetLeaseByID fetches a lease by its ID and returns typed data
@@ -86,119 +69,87 @@ func (c *Client) GetLeaseByID(ctx context.Context, req *GetLeaseByIDRequest) (*G
 	if req == nil || req.LeaseID == "" {
 		return nil, &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("LeaseID is required")}
 	}
-	leaseURL := fmt.Sprintf("%s/leases/%s", c.BaseURL, req.LeaseID)
-	resp, err := c.doGet(ctx, leaseURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var wrapper struct {
-		Status string `json:"status"`
-		Data   Lease  `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&wrapper); err != nil {
-		return nil, &JSONDecodingError{Err: err}
-	}
-	return &GetLeaseByIDResponse{Lease: wrapper.Data}, nil
+	call := apiCall{method: http.MethodGet, path: "/leases/" + req.LeaseID}
+	return invoke(ctx, c, "GetLeaseByID", req, call, func(ctx context.Context) (*GetLeaseByIDResponse, error) {
+		lease, err := do[Lease](ctx, c, call)
+		if err != nil {
+			return nil, err
+		}
+		return &GetLeaseByIDResponse{Lease: lease}, nil
+	})
 }
 
 // CreateLease requests a new lease and returns the created Lease in a response struct
 func (c *Client) CreateLease(ctx context.Context, req *CreateLeaseRequest) (*CreateLeaseResponse, error) {
-	if req == nil || req.LeaseTemplateUUID == "" {
-		return nil, &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("LeaseTemplateUUID is required")}
-	}
-	leaseURL := c.BaseURL + "/leases"
-	body := map[string]interface{}{
-		"leaseTemplateUuid": req.LeaseTemplateUUID,
-	}
-	if req.Comments != "" {
-		body["comments"] = req.Comments
-	}
-	b, _ := json.Marshal(body)
-	resp, err := c.doPost(ctx, leaseURL, b)
+	req, err := c.resolveCreateLeaseRequest(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var wrapper struct {
-		Status string `json:"status"`
-		Data   Lease  `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&wrapper); err != nil {
-		return nil, &JSONDecodingError{Err: err}
-	}
-
-	leaseIdComponents := map[string]string{
-		"userEmail": wrapper.Data.UserEmail,
-		"uuid":      wrapper.Data.UUID,
-	}
-
-	leaseId, err := json.Marshal(leaseIdComponents)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal lease ID components: %w", err)
-	}
-
-	wrapper.Data.LeaseId = b64.StdEncoding.EncodeToString(leaseId)
-
-	return &CreateLeaseResponse{Lease: wrapper.Data}, nil
+	call := createLeaseCall(req, "")
+	return invoke(ctx, c, "CreateLease", req, call, func(ctx context.Context) (*CreateLeaseResponse, error) {
+		return c.createLease(ctx, call)
+	})
 }
 
 // CreateLeaseAsUser creates a lease as a different user by generating a JWT for that user and using it for the request only.
 func (c *Client) CreateLeaseAsUser(ctx context.Context, req *CreateLeaseRequest, userEmail string, jwtSecret string) (*CreateLeaseResponse, error) {
-	if req == nil || req.LeaseTemplateUUID == "" {
-		return nil, &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("LeaseTemplateUUID is required")}
-	}
-	leaseURL := c.BaseURL + "/leases"
-	body := map[string]interface{}{
-		"leaseTemplateUuid": req.LeaseTemplateUUID,
-	}
-	if req.Comments != "" {
-		body["comments"] = req.Comments
+	req, err := c.resolveCreateLeaseRequest(ctx, req)
+	if err != nil {
+		return nil, err
 	}
-	b, _ := json.Marshal(body)
 
 	// Generate JWT using helper
 	userClaims := NewUserUserClaims(userEmail)
 	jwt, err := GenerateJWT(userClaims, jwtSecret, 15*time.Minute)
 	if err != nil {
-		return nil, &APIRequestError{Op: "jwt_gen", URL: leaseURL, Err: err}
+		return nil, &APIRequestError{Op: "jwt_gen", URL: c.BaseURL + "/leases", Err: err}
 	}
+	call := createLeaseCall(req, jwt)
+	return invoke(ctx, c, "CreateLeaseAsUser", req, call, func(ctx context.Context) (*CreateLeaseResponse, error) {
+		return c.createLease(ctx, call)
+	})
+}
 
-	// Use a custom request with the user JWT, but otherwise match doPost logic
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", leaseURL, bytes.NewReader(b))
-	if err != nil {
-		return nil, &APIRequestError{Op: "new_request", URL: leaseURL, Err: err}
+// resolveCreateLeaseRequest checks req and returns a copy with the UUID of its
+// LeaseTemplateName when LeaseTemplateUUID is empty.
+func (c *Client) resolveCreateLeaseRequest(ctx context.Context, req *CreateLeaseRequest) (*CreateLeaseRequest, error) {
+	if req == nil || (req.LeaseTemplateUUID == "" && req.LeaseTemplateName == "") {
+		return nil, &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("LeaseTemplateUUID or LeaseTemplateName is required")}
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+jwt)
-
-	// Use a custom HTTP client that does NOT use the default authTransport for this request
-	customClient := &http.Client{
-		Timeout: c.HTTPClient.Timeout,
+	if req.LeaseTemplateUUID != "" {
+		return req, nil
 	}
-	resp, err := customClient.Do(httpReq)
+	uuid, err := c.ResolveLeaseTemplateUUID(ctx, req.LeaseTemplateName)
 	if err != nil {
-		return nil, &APIRequestError{Op: "do", URL: leaseURL, Err: err}
+		return nil, err
 	}
-	defer resp.Body.Close()
+	resolved := *req
+	resolved.LeaseTemplateUUID = uuid
+	return &resolved, nil
+}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, DecodeAPIError(b, resp)
+// createLeaseCall builds the POST /leases request for req, authenticated with token instead of
+// the client token when set.
+func createLeaseCall(req *CreateLeaseRequest, token string) apiCall {
+	body := map[string]interface{}{
+		"leaseTemplateUuid": req.LeaseTemplateUUID,
 	}
-
-	var wrapper struct {
-		Status string `json:"status"`
-		Data   Lease  `json:"data"`
+	if req.Comments != "" {
+		body["comments"] = req.Comments
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&wrapper); err != nil {
-		return nil, &JSONDecodingError{Err: err}
+	return apiCall{method: http.MethodPost, path: "/leases", body: body, token: token}
+}
+
+// createLease performs call and fills in the LeaseId of the created lease.
+func (c *Client) createLease(ctx context.Context, call apiCall) (*CreateLeaseResponse, error) {
+	lease, err := do[Lease](ctx, c, call)
+	if err != nil {
+		return nil, err
 	}
 
 	leaseIdComponents := map[string]string{
-		"userEmail": wrapper.Data.UserEmail,
-		"uuid":      wrapper.Data.UUID,
+		"userEmail": lease.UserEmail,
+		"uuid":      lease.UUID,
 	}
 
 	leaseId, err := json.Marshal(leaseIdComponents)
@@ -206,131 +157,125 @@ func (c *Client) CreateLeaseAsUser(ctx context.Context, req *CreateLeaseRequest,
 		return nil, fmt.Errorf("failed to marshal lease ID components: %w", err)
 	}
 
-	wrapper.Data.LeaseId = b64.StdEncoding.EncodeToString(leaseId)
+	lease.LeaseId = b64.StdEncoding.EncodeToString(leaseId)
 
-	return &CreateLeaseResponse{Lease: wrapper.Data}, nil
+	return &CreateLeaseResponse{Lease: lease}, nil
 }
 
 // GetLeaseTemplates fetches lease templates and returns typed data
 func (c *Client) GetLeaseTemplates(ctx context.Context, req QueryBuilder) (*GetLeaseTemplatesResponse, error) {
-	u, err := url.Parse(c.BaseURL + "/leaseTemplates")
-	if err != nil {
-		return nil, &APIRequestError{Op: "parse", URL: c.BaseURL + "/leaseTemplates", Err: err}
-	}
-
-	if req != nil {
-		u.RawQuery = req.BuildQuery().Encode()
-	}
-
-	resp, err := c.doGet(ctx, u.String())
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var wrapper struct {
-		Status string                    `json:"status"`
-		Data   GetLeaseTemplatesResponse `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&wrapper); err != nil {
-		return nil, &JSONDecodingError{Err: err}
-	}
-
-	return &wrapper.Data, nil
+	call := apiCall{method: http.MethodGet, path: "/leaseTemplates", query: c.listQuery(req)}
+	return invoke(ctx, c, "GetLeaseTemplates", req, call, func(ctx context.Context) (*GetLeaseTemplatesResponse, error) {
+		page, err := do[Page[LeaseTemplate]](ctx, c, call)
+		if err != nil {
+			return nil, err
+		}
+		return &GetLeaseTemplatesResponse{LeaseTemplates: page.Result, NextPageIdentifier: page.NextPageIdentifier}, nil
+	})
 }
 
 // FetchAllLeases fetches all leases using pagination
 func (c *Client) FetchAllLeases(ctx context.Context, req *GetLeasesRequest) (*GetLeasesResponse, error) {
-	allLeases, err := paginateAll(ctx, req, func(ctx context.Context, r *GetLeasesRequest) ([]Lease, string, error) {
-		resp, err := c.GetLeases(ctx, r)
+	return invoke(ctx, c, "FetchAllLeases", req, apiCall{}, func(ctx context.Context) (*GetLeasesResponse, error) {
+		allLeases, err := paginateAll(ctx, req, func(ctx context.Context, r *GetLeasesRequest) ([]Lease, string, error) {
+			resp, err := c.GetLeases(ctx, r)
+			if err != nil {
+				return nil, "", err
+			}
+			return resp.Leases, resp.NextPageIdentifier, nil
+		})
 		if err != nil {
-			return nil, "", err
+			return nil, err
 		}
-		return resp.Leases, resp.NextPageIdentifier, nil
+		return &GetLeasesResponse{Leases: allLeases}, nil
 	})
-	if err != nil {
-		return nil, err
-	}
-	return &GetLeasesResponse{Leases: allLeases}, nil
 }
 
 // FetchAllLeaseTemplates fetches all lease templates using pagination
 func (c *Client) FetchAllLeaseTemplates(ctx context.Context, req *GetLeaseTemplatesRequest) (*GetLeaseTemplatesResponse, error) {
-	allTemplates, err := paginateAll(ctx, req, func(ctx context.Context, r *GetLeaseTemplatesRequest) ([]LeaseTemplate, string, error) {
-		resp, err := c.GetLeaseTemplates(ctx, r)
+	return invoke(ctx, c, "FetchAllLeaseTemplates", req, apiCall{}, func(ctx context.Context) (*GetLeaseTemplatesResponse, error) {
+		allTemplates, err := paginateAll(ctx, req, func(ctx context.Context, r *GetLeaseTemplatesRequest) ([]LeaseTemplate, string, error) {
+			resp, err := c.GetLeaseTemplates(ctx, r)
+			if err != nil {
+				return nil, "", err
+			}
+			return resp.LeaseTemplates, resp.NextPageIdentifier, nil
+		})
 		if err != nil {
-			return nil, "", err
+			return nil, err
 		}
-		return resp.LeaseTemplates, resp.NextPageIdentifier, nil
+		return &GetLeaseTemplatesResponse{LeaseTemplates: allTemplates}, nil
 	})
-	if err != nil {
-		return nil, err
-	}
-	return &GetLeaseTemplatesResponse{LeaseTemplates: allTemplates}, nil
 }
 
 // GetAccounts fetches accounts and returns typed data
 func (c *Client) GetAccounts(ctx context.Context, req QueryBuilder) (*GetAccountsResponse, error) {
-	u, err := url.Parse(c.BaseURL + "/accounts")
-	if err != nil {
-		return nil, &APIRequestError{Op: "parse", URL: c.BaseURL + "/accounts", Err: err}
-	}
-
-	if req != nil {
-		u.RawQuery = req.BuildQuery().Encode()
-	}
-
-	resp, err := c.doGet(ctx, u.String())
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var wrapper struct {
-		Status string              `json:"status"`
-		Data   GetAccountsResponse `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&wrapper); err != nil {
-		return nil, &JSONDecodingError{Err: err}
-	}
-
-	return &wrapper.Data, nil
+	call := apiCall{method: http.MethodGet, path: "/accounts", query: c.listQuery(req)}
+	return invoke(ctx, c, "GetAccounts", req, call, func(ctx context.Context) (*GetAccountsResponse, error) {
+		page, err := do[Page[Account]](ctx, c, call)
+		if err != nil {
+			return nil, err
+		}
+		return &GetAccountsResponse{Accounts: page.Result, NextPageIdentifier: page.NextPageIdentifier}, nil
+	})
 }
 
 // FetchAllAccounts fetches all accounts using pagination
 func (c *Client) FetchAllAccounts(ctx context.Context, req *GetAccountsRequest) (*GetAccountsResponse, error) {
-	allAccounts, err := paginateAll(ctx, req, func(ctx context.Context, r *GetAccountsRequest) ([]Account, string, error) {
-		resp, err := c.GetAccounts(ctx, r)
+	return invoke(ctx, c, "FetchAllAccounts", req, apiCall{}, func(ctx context.Context) (*GetAccountsResponse, error) {
+		allAccounts, err := paginateAll(ctx, req, func(ctx context.Context, r *GetAccountsRequest) ([]Account, string, error) {
+			resp, err := c.GetAccounts(ctx, r)
+			if err != nil {
+				return nil, "", err
+			}
+			return resp.Accounts, resp.NextPageIdentifier, nil
+		})
 		if err != nil {
-			return nil, "", err
+			return nil, err
 		}
-		return resp.Accounts, resp.NextPageIdentifier, nil
+		return &GetAccountsResponse{Accounts: allAccounts}, nil
 	})
-	if err != nil {
-		return nil, err
-	}
-	return &GetAccountsResponse{Accounts: allAccounts}, nil
 }
 
-// GetConfigurations fetches the global configuration
-func (c *Client) GetConfigurations(ctx context.Context) (*GlobalConfiguration, error) {
-	configURL := c.BaseURL + "/configurations"
-	resp, err := c.doGet(ctx, configURL)
-	err = nil
-	if err != nil {
-		return nil, err
-	}
+// GetUnregisteredAccounts fetches accounts in the entry OU that are not registered with the sandbox
+func (c *Client) GetUnregisteredAccounts(ctx context.Context, req QueryBuilder) (*GetUnregisteredAccountsResponse, error) {
+	call := apiCall{method: http.MethodGet, path: "/accounts/unregistered", query: c.listQuery(req)}
+	return invoke(ctx, c, "GetUnregisteredAccounts", req, call, func(ctx context.Context) (*GetUnregisteredAccountsResponse, error) {
+		page, err := do[Page[UnregisteredAccount]](ctx, c, call)
+		if err != nil {
+			return nil, err
+		}
+		return &GetUnregisteredAccountsResponse{UnregisteredAccounts: page.Result, NextPageIdentifier: page.NextPageIdentifier}, nil
+	})
+}
 
-	defer resp.Body.Close()
-	var wrapper struct {
-		Status string              `json:"status"`
-		Data   GlobalConfiguration `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&wrapper); err != nil {
-		return nil, &JSONDecodingError{Err: err}
-	}
+// FetchAllUnregisteredAccounts fetches all unregistered accounts using pagination
+func (c *Client) FetchAllUnregisteredAccounts(ctx context.Context, req *GetUnregisteredAccountsRequest) (*GetUnregisteredAccountsResponse, error) {
+	return invoke(ctx, c, "FetchAllUnregisteredAccounts", req, apiCall{}, func(ctx context.Context) (*GetUnregisteredAccountsResponse, error) {
+		allAccounts, err := paginateAll(ctx, req, func(ctx context.Context, r *GetUnregisteredAccountsRequest) ([]UnregisteredAccount, string, error) {
+			resp, err := c.GetUnregisteredAccounts(ctx, r)
+			if err != nil {
+				return nil, "", err
+			}
+			return resp.UnregisteredAccounts, resp.NextPageIdentifier, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &GetUnregisteredAccountsResponse{UnregisteredAccounts: allAccounts}, nil
+	})
+}
 
-	return &wrapper.Data, nil
+// GetConfigurations fetches the global configuration
+func (c *Client) GetConfigurations(ctx context.Context) (*GlobalConfiguration, error) {
+	call := apiCall{method: http.MethodGet, path: "/configurations"}
+	return invoke(ctx, c, "GetConfigurations", nil, call, func(ctx context.Context) (*GlobalConfiguration, error) {
+		config, err := do[GlobalConfiguration](ctx, c, call)
+		if err != nil {
+			return nil, err
+		}
+		return &config, nil
+	})
 }
 
 // UpdateLease updates a lease by leaseId (PATCH /leases/{leaseId})
@@ -338,24 +283,14 @@ func (c *Client) UpdateLease(ctx context.Context, req *UpdateLeaseRequest) (*Upd
 	if req == nil || req.LeaseID == "" {
 		return nil, &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("LeaseID is required")}
 	}
-	urlStr := c.BaseURL + "/leases/" + req.LeaseID
-	body, err := json.Marshal(req)
-	if err != nil {
-		return nil, &APIRequestError{Op: "marshal", URL: urlStr, Err: err}
-	}
-	resp, err := c.doPatch(ctx, urlStr, body)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	var wrapper struct {
-		Status string `json:"status"`
-		Data   Lease  `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&wrapper); err != nil {
-		return nil, &JSONDecodingError{Err: err}
-	}
-	return &UpdateLeaseResponse{Lease: wrapper.Data}, nil
+	call := apiCall{method: http.MethodPatch, path: "/leases/" + req.LeaseID, body: req}
+	return invoke(ctx, c, "UpdateLease", req, call, func(ctx context.Context) (*UpdateLeaseResponse, error) {
+		lease, err := do[Lease](ctx, c, call)
+		if err != nil {
+			return nil, err
+		}
+		return &UpdateLeaseResponse{Lease: lease}, nil
+	})
 }
 
 // ReviewLease reviews (approve/deny) a lease (POST /leases/{leaseId}/review)
@@ -363,17 +298,8 @@ func (c *Client) ReviewLease(ctx context.Context, req *ReviewLeaseRequest) error
 	if req == nil || req.LeaseID == "" || (req.Action != ReviewApprove && req.Action != ReviewDeny) {
 		return &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("LeaseID and Action are required")}
 	}
-	urlStr := c.BaseURL + "/leases/" + req.LeaseID + "/review"
-	body, err := json.Marshal(map[string]string{"action": req.Action})
-	if err != nil {
-		return &APIRequestError{Op: "marshal", URL: urlStr, Err: err}
-	}
-	resp, err := c.doPost(ctx, urlStr, body)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	return nil
+	body := map[string]string{"action": req.Action}
+	return invokeNoData(ctx, c, "ReviewLease", req, apiCall{method: http.MethodPost, path: "/leases/" + req.LeaseID + "/review", body: body})
 }
 
 // FreezeLease freezes an active lease (POST /leases/{leaseId}/freeze)
@@ -381,13 +307,7 @@ func (c *Client) FreezeLease(ctx context.Context, req *FreezeLeaseRequest) error
 	if req == nil || req.LeaseID == "" {
 		return &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("LeaseID is required")}
 	}
-	urlStr := c.BaseURL + "/leases/" + req.LeaseID + "/freeze"
-	resp, err := c.doPost(ctx, urlStr, nil)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	return nil
+	return invokeNoData(ctx, c, "FreezeLease", req, apiCall{method: http.MethodPost, path: "/leases/" + req.LeaseID + "/freeze"})
 }
 
 // TerminateLease terminates an active lease (POST /leases/{leaseId}/terminate)
@@ -395,13 +315,7 @@ func (c *Client) TerminateLease(ctx context.Context, req *TerminateLeaseRequest)
 	if req == nil || req.LeaseID == "" {
 		return &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("LeaseID is required")}
 	}
-	urlStr := c.BaseURL + "/leases/" + req.LeaseID + "/terminate"
-	resp, err := c.doPost(ctx, urlStr, nil)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	return nil
+	return invokeNoData(ctx, c, "TerminateLease", req, apiCall{method: http.MethodPost, path: "/leases/" + req.LeaseID + "/terminate"})
 }
 
 // UpdateLeaseTemplate updates a lease template (PUT /leaseTemplates/{leaseTemplateId})
@@ -409,24 +323,108 @@ func (c *Client) UpdateLeaseTemplate(ctx context.Context, req *UpdateLeaseTempla
 	if req == nil || req.LeaseTemplateID == "" {
 		return nil, &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("LeaseTemplateID is required")}
 	}
-	urlStr := c.BaseURL + "/leaseTemplates/" + req.LeaseTemplateID
-	body, err := json.Marshal(req)
-	if err != nil {
-		return nil, &APIRequestError{Op: "marshal", URL: urlStr, Err: err}
+	call := apiCall{method: http.MethodPut, path: "/leaseTemplates/" + req.LeaseTemplateID, body: req}
+	return invoke(ctx, c, "UpdateLeaseTemplate", req, call, func(ctx context.Context) (*UpdateLeaseTemplateResponse, error) {
+		defer c.templates.invalidate()
+		tpl, err := do[LeaseTemplate](ctx, c, call)
+		if err != nil {
+			return nil, err
+		}
+		return &UpdateLeaseTemplateResponse{LeaseTemplate: tpl}, nil
+	})
+}
+
+// GetLeaseTemplateByID fetches a lease template by its ID (GET /leaseTemplates/{leaseTemplateId})
+func (c *Client) GetLeaseTemplateByID(ctx context.Context, req *GetLeaseTemplateByIDRequest) (*GetLeaseTemplateByIDResponse, error) {
+	if req == nil || req.LeaseTemplateID == "" {
+		return nil, &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("LeaseTemplateID is required")}
 	}
-	resp, err := c.doPut(ctx, urlStr, body)
+	call := apiCall{method: http.MethodGet, path: "/leaseTemplates/" + req.LeaseTemplateID}
+	return invoke(ctx, c, "GetLeaseTemplateByID", req, call, func(ctx context.Context) (*GetLeaseTemplateByIDResponse, error) {
+		tpl, err := do[LeaseTemplate](ctx, c, call)
+		if err != nil {
+			return nil, err
+		}
+		return &GetLeaseTemplateByIDResponse{LeaseTemplate: tpl}, nil
+	})
+}
+
+// PatchLeaseTemplate applies mutate to the current version of a lease template and writes the
+// result back with a full-replacement PUT. Fields left untouched by mutate, including any the
+// client does not model, are preserved.
+//
+// Conflict detection is best-effort: the API has no conditional update, so an edit made by
+// someone else can still be overwritten. Before writing, the template is fetched again and its
+// Meta.LastEditTime compared with the version mutate was applied to; if they differ no write is
+// made. After writing, it is fetched once more and compared with the PUT response; if they
+// differ the template was edited around the write, which may have been lost or may have
+// overwritten that edit. Both return a *LeaseTemplateModifiedError, with Written set in the
+// second case. Reads bypass any Cache installed on the client.
+func (c *Client) PatchLeaseTemplate(ctx context.Context, leaseTemplateID string, mutate func(*LeaseTemplate)) (*UpdateLeaseTemplateResponse, error) {
+	if leaseTemplateID == "" || mutate == nil {
+		return nil, &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("LeaseTemplateID and mutate are required")}
+	}
+	getReq := &GetLeaseTemplateByIDRequest{LeaseTemplateID: leaseTemplateID}
+	return invoke(ctx, c, "PatchLeaseTemplate", getReq, apiCall{}, func(ctx context.Context) (*UpdateLeaseTemplateResponse, error) {
+		original, err := c.GetLeaseTemplateByID(withoutCachedValue(ctx), getReq)
+		if err != nil {
+			return nil, err
+		}
+
+		// The response may be shared with a Cache, so mutate works on a deep copy.
+		tpl, err := cloneLeaseTemplate(original.LeaseTemplate)
+		if err != nil {
+			return nil, err
+		}
+		mutate(&tpl)
+
+		current, err := c.GetLeaseTemplateByID(withoutCachedValue(ctx), getReq)
+		if err != nil {
+			return nil, err
+		}
+		if current.LeaseTemplate.Meta.LastEditTime != original.LeaseTemplate.Meta.LastEditTime {
+			return nil, &LeaseTemplateModifiedError{
+				LeaseTemplateID:      leaseTemplateID,
+				ExpectedLastEditTime: original.LeaseTemplate.Meta.LastEditTime,
+				ActualLastEditTime:   current.LeaseTemplate.Meta.LastEditTime,
+			}
+		}
+
+		updateReq := NewUpdateLeaseTemplateRequest(tpl)
+		updateReq.LeaseTemplateID = leaseTemplateID
+		updated, err := c.UpdateLeaseTemplate(ctx, updateReq)
+		if err != nil {
+			return nil, err
+		}
+
+		written := updated.LeaseTemplate.Meta.LastEditTime
+		after, err := c.GetLeaseTemplateByID(withoutCachedValue(ctx), getReq)
+		if err != nil {
+			return nil, err
+		}
+		if written != "" && after.LeaseTemplate.Meta.LastEditTime != written {
+			return updated, &LeaseTemplateModifiedError{
+				LeaseTemplateID:      leaseTemplateID,
+				ExpectedLastEditTime: written,
+				ActualLastEditTime:   after.LeaseTemplate.Meta.LastEditTime,
+				Written:              true,
+			}
+		}
+		return updated, nil
+	})
+}
+
+// cloneLeaseTemplate returns a deep copy of tpl, including its unknown fields.
+func cloneLeaseTemplate(tpl LeaseTemplate) (LeaseTemplate, error) {
+	var clone LeaseTemplate
+	b, err := json.Marshal(tpl)
 	if err != nil {
-		return nil, err
+		return clone, &APIRequestError{Op: "marshal", URL: "", Err: err}
 	}
-	defer resp.Body.Close()
-	var wrapper struct {
-		Status string        `json:"status"`
-		Data   LeaseTemplate `json:"data"`
+	if err := json.Unmarshal(b, &clone); err != nil {
+		return clone, &APIRequestError{Op: "marshal", URL: "", Err: err}
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&wrapper); err != nil {
-		return nil, &JSONDecodingError{Err: err}
-	}
-	return &UpdateLeaseTemplateResponse{LeaseTemplate: wrapper.Data}, nil
+	return clone, nil
 }
 
 // DeleteLeaseTemplate deletes a lease template (DELETE /leaseTemplates/{leaseTemplateId})
@@ -434,35 +432,20 @@ func (c *Client) DeleteLeaseTemplate(ctx context.Context, req *DeleteLeaseTempla
 	if req == nil || req.LeaseTemplateID == "" {
 		return &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("LeaseTemplateID is required")}
 	}
-	urlStr := c.BaseURL + "/leaseTemplates/" + req.LeaseTemplateID
-	resp, err := c.doDelete(ctx, urlStr)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	return nil
+	defer c.templates.invalidate()
+	return invokeNoData(ctx, c, "DeleteLeaseTemplate", req, apiCall{method: http.MethodDelete, path: "/leaseTemplates/" + req.LeaseTemplateID})
 }
 
 // RegisterAccount registers an account (POST /accounts)
 func (c *Client) RegisterAccount(ctx context.Context, req *RegisterAccountRequest) (*RegisterAccountResponse, error) {
-	urlStr := c.BaseURL + "/accounts"
-	body, err := json.Marshal(req)
-	if err != nil {
-		return nil, &APIRequestError{Op: "marshal", URL: urlStr, Err: err}
-	}
-	resp, err := c.doPost(ctx, urlStr, body)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	var wrapper struct {
-		Status string  `json:"status"`
-		Data   Account `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&wrapper); err != nil {
-		return nil, &JSONDecodingError{Err: err}
-	}
-	return &RegisterAccountResponse{Account: wrapper.Data}, nil
+	call := apiCall{method: http.MethodPost, path: "/accounts", body: req}
+	return invoke(ctx, c, "RegisterAccount", req, call, func(ctx context.Context) (*RegisterAccountResponse, error) {
+		account, err := do[Account](ctx, c, call)
+		if err != nil {
+			return nil, err
+		}
+		return &RegisterAccountResponse{Account: account}, nil
+	})
 }
 
 // RetryCleanup retries cleanup for an account (POST /accounts/{awsAccountId}/retryCleanup)
@@ -470,13 +453,7 @@ func (c *Client) RetryCleanup(ctx context.Context, req *RetryCleanupRequest) err
 	if req == nil || req.AwsAccountId == "" {
 		return &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("AwsAccountId is required")}
 	}
-	urlStr := c.BaseURL + "/accounts/" + req.AwsAccountId + "/retryCleanup"
-	resp, err := c.doPost(ctx, urlStr, nil)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	return nil
+	return invokeNoData(ctx, c, "RetryCleanup", req, apiCall{method: http.MethodPost, path: "/accounts/" + req.AwsAccountId + "/retryCleanup"})
 }
 
 // EjectAccount ejects an account from the sandbox (POST /accounts/{awsAccountId}/eject)
@@ -484,13 +461,7 @@ func (c *Client) EjectAccount(ctx context.Context, req *EjectAccountRequest) err
 	if req == nil || req.AwsAccountId == "" {
 		return &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("AwsAccountId is required")}
 	}
-	urlStr := c.BaseURL + "/accounts/" + req.AwsAccountId + "/eject"
-	resp, err := c.doPost(ctx, urlStr, nil)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	return nil
+	return invokeNoData(ctx, c, "EjectAccount", req, apiCall{method: http.MethodPost, path: "/accounts/" + req.AwsAccountId + "/eject"})
 }
 
 // paginateAll is a generic helper for paginated API fetches (no reflection needed)
@@ -500,8 +471,8 @@ func paginateAll[T any, R PageIdentifiable](
 	fetchPage func(context.Context, R) ([]T, string, error),
 ) ([]T, error) {
 	var allItems []T
-	for {
-		items, nextPage, err := fetchPage(ctx, req)
+	for page := 1; ; page++ {
+		items, nextPage, err := fetchPage(context.WithValue(ctx, pageKey{}, page), req)
 		if err != nil {
 			return nil, err
 		}
@@ -515,168 +486,212 @@ func paginateAll[T any, R PageIdentifiable](
 	return allItems, nil
 }
 
-// doGet is a helper for making GET requests and handling common errors.
-func (c *Client) doGet(ctx context.Context, url string) (*http.Response, error) {
-	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, &APIRequestError{Op: "new_request", URL: url, Err: err}
-	}
+// Do calls an arbitrary API endpoint through the same pipeline as the typed methods, for
+// endpoints this library does not wrap yet. path is relative to BaseURL (e.g.
+// "/accounts/unregistered"), query may be nil, and body, when non-nil, is sent as JSON
+// ([]byte and json.RawMessage are sent as-is). On success the envelope's data member is
+// decoded into out, which may be nil to discard it. Failures are returned as the same
+// typed errors the other methods return.
+//
+// Middleware sees these calls with the operation name "Do" and body as the request.
+func (c *Client) Do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	call := apiCall{method: method, path: path, query: query, body: body}
+	_, err := invoke(ctx, c, "Do", body, call, func(ctx context.Context) (any, error) {
+		return out, c.send(ctx, call, out)
+	})
+	return err
+}
 
-	resp, err := c.HTTPClient.Do(httpReq)
-	if err != nil {
-		return nil, &APIRequestError{Op: "do", URL: url, Err: err}
-	}
+// apiCall describes a single API request.
+type apiCall struct {
+	method string
+	path   string // relative to BaseURL
+	query  url.Values
+	body   any    // encoded as JSON when non-nil
+	token  string // overrides Client.Token when set
+	raw    bool   // the response is not wrapped in the {status, data} envelope
+}
 
-	if isJSON, body := isJSONResponse(resp); !isJSON {
-		defer resp.Body.Close()
-		return nil, &APIRequestError{
-			Op:  "doGet",
-			URL: url,
-			Err: fmt.Errorf("non-JSON response (%s): %s", resp.Header.Get("Content-Type"), body),
-		}
-	}
+// noData is the result type for calls whose response carries no data.
+type noData struct{}
 
-	if resp.StatusCode != http.StatusOK {
-		defer resp.Body.Close()
-		return nil, DecodeAPIError(nil, resp)
-	}
-	return resp, nil
+// UnmarshalJSON discards whatever data the response carried.
+func (*noData) UnmarshalJSON([]byte) error { return nil }
+
+// do runs call through the request pipeline and returns the decoded envelope data.
+func do[T any](ctx context.Context, c *Client, call apiCall) (T, error) {
+	var data T
+	err := c.send(ctx, call, &data)
+	return data, err
 }
 
-// doPost is a helper for making POST requests and handling common errors.
-func (c *Client) doPost(ctx context.Context, url string, body []byte) (*http.Response, error) {
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
-	if err != nil {
-		return nil, &APIRequestError{Op: "new_request", URL: url, Err: err}
-	}
+// invokeNoData runs a single-request operation whose response carries no data.
+func invokeNoData(ctx context.Context, c *Client, op string, req any, call apiCall) error {
+	_, err := invoke(ctx, c, op, req, call, func(ctx context.Context) (noData, error) {
+		return do[noData](ctx, c, call)
+	})
+	return err
+}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	resp, err := c.HTTPClient.Do(httpReq)
+// send builds, authenticates and performs call, checks the response status and decodes
+// the data member of the response envelope into out (discarded when out is nil).
+func (c *Client) send(ctx context.Context, call apiCall, out any) error {
+	u, err := url.Parse(c.BaseURL + call.path)
 	if err != nil {
-		return nil, &APIRequestError{Op: "do", URL: url, Err: err}
+		return &APIRequestError{Op: "parse", URL: c.BaseURL + call.path, Err: err}
 	}
-
-	if isJSON, body := isJSONResponse(resp); !isJSON {
-		defer resp.Body.Close()
-		return nil, &APIRequestError{
-			Op:  "doPost",
-			URL: url,
-			Err: fmt.Errorf("non-JSON response (%s): %s", resp.Header.Get("Content-Type"), body),
-		}
-	}
-
-	// Accept 200 or 201 as success for POST
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		defer resp.Body.Close()
-		return nil, DecodeAPIError(body, resp)
+	if len(call.query) > 0 {
+		u.RawQuery = call.query.Encode()
 	}
-	return resp, nil
-}
+	urlStr := u.String()
 
-// doPatch is a helper for making PATCH requests and handling common errors.
-func (c *Client) doPatch(ctx context.Context, url string, body []byte) (*http.Response, error) {
-	httpReq, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewReader(body))
+	reqBody, err := encodeBody(call.body)
 	if err != nil {
-		return nil, &APIRequestError{Op: "new_request", URL: url, Err: err}
+		return &APIRequestError{Op: "marshal", URL: urlStr, Err: err}
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	resp, err := c.HTTPClient.Do(httpReq)
+	var bodyReader io.Reader
+	if reqBody != nil {
+		bodyReader = bytes.NewReader(reqBody)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, call.method, urlStr, bodyReader)
 	if err != nil {
-		return nil, &APIRequestError{Op: "do", URL: url, Err: err}
+		return &APIRequestError{Op: "new_request", URL: urlStr, Err: err}
 	}
-
-	if isJSON, body := isJSONResponse(resp); !isJSON {
-		defer resp.Body.Close()
-		return nil, &APIRequestError{
-			Op:  "doPatch",
-			URL: url,
-			Err: fmt.Errorf("non-JSON response (%s): %s", resp.Header.Get("Content-Type"), body),
+	if reqBody != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	mwCall := callFromContext(ctx)
+	token := c.Token
+	switch {
+	case call.token != "":
+		token = call.token
+	case mwCall != nil && mwCall.token != "":
+		token = mwCall.token
+	}
+	if mwCall != nil && mwCall.checkToken != nil {
+		if err := mwCall.checkToken(token); err != nil {
+			return err
 		}
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		defer resp.Body.Close()
-		return nil, DecodeAPIError(body, resp)
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
 	}
-	return resp, nil
-}
-
-// doPut is a helper for making PUT requests and handling common errors.
-func (c *Client) doPut(ctx context.Context, url string, body []byte) (*http.Response, error) {
-	httpReq, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(body))
-	if err != nil {
-		return nil, &APIRequestError{Op: "new_request", URL: url, Err: err}
+	if mwCall != nil {
+		for name, values := range mwCall.Header {
+			httpReq.Header[name] = append(httpReq.Header[name], values...)
+		}
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
+	started := time.Now()
 	resp, err := c.HTTPClient.Do(httpReq)
 	if err != nil {
-		return nil, &APIRequestError{Op: "do", URL: url, Err: err}
+		return &APIRequestError{Op: "do", URL: urlStr, Err: err}
 	}
+	defer resp.Body.Close()
+	var envelopeStatus string
+	defer func() { recordResponse(ctx, resp, started, envelopeStatus) }()
 
-	if isJSON, body := isJSONResponse(resp); !isJSON {
-		defer resp.Body.Close()
-		return nil, &APIRequestError{
-			Op:  "doPut",
-			URL: url,
-			Err: fmt.Errorf("non-JSON response (%s): %s", resp.Header.Get("Content-Type"), body),
-		}
+	if err := checkContentLength(resp, c.maxResponseSize()); err != nil {
+		return err
 	}
+	body := acquireBodyReader(resp.Body, c.maxResponseSize())
+	defer releaseBodyReader(body)
 
-	if resp.StatusCode != http.StatusOK {
-		defer resp.Body.Close()
-		return nil, DecodeAPIError(body, resp)
+	if isJSON, head := isJSONResponse(resp, body); !isJSON {
+		return &APIRequestError{
+			Op:  strings.ToLower(call.method),
+			URL: urlStr,
+			Err: fmt.Errorf("non-JSON response (%s): %s", resp.Header.Get("Content-Type"), head),
+		}
 	}
-	return resp, nil
-}
 
-// doDelete is a helper for making DELETE requests and handling common errors.
-func (c *Client) doDelete(ctx context.Context, url string) (*http.Response, error) {
-	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
-	if err != nil {
-		return nil, &APIRequestError{Op: "new_request", URL: url, Err: err}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var err error
+		envelopeStatus, err = decodeAPIErrorFrom(reqBody, resp, body)
+		return err
 	}
 
-	resp, err := c.HTTPClient.Do(httpReq)
-	if err != nil {
-		return nil, &APIRequestError{Op: "do", URL: url, Err: err}
+	if out == nil {
+		out = &noData{}
 	}
-
-	if isJSON, body := isJSONResponse(resp); !isJSON {
-		defer resp.Body.Close()
-		return nil, &APIRequestError{
-			Op:  "doDelete",
-			URL: url,
-			Err: fmt.Errorf("non-JSON response (%s): %s", resp.Header.Get("Content-Type"), body),
+	envelope := struct {
+		Status string `json:"status"`
+		Data   any    `json:"data"`
+	}{Data: out}
+	var target any = &envelope
+	if call.raw {
+		target = out
+	}
+	var src io.Reader = body
+	if mwCall != nil && mwCall.captureBody {
+		raw, err := io.ReadAll(body)
+		if err != nil {
+			return decodeError(err)
 		}
+		mwCall.responseBody = raw
+		src = bytes.NewReader(raw)
 	}
+	if err := json.NewDecoder(src).Decode(target); err != nil && err != io.EOF {
+		return decodeError(err)
+	}
+	envelopeStatus = envelope.Status
+	drainBody(body)
+	return nil
+}
 
-	// Accept 200 or 204 as success for DELETE
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		defer resp.Body.Close()
-		return nil, DecodeAPIError(nil, resp)
+// decodeError maps an error reading or decoding a response body onto the error returned.
+func decodeError(err error) error {
+	var tooLarge *ResponseTooLargeError
+	if errors.As(err, &tooLarge) {
+		return tooLarge
 	}
-	return resp, nil
+	return &JSONDecodingError{Err: err}
 }
 
-// isJSONResponse returns true if the Content-Type is json or the body is empty
-func isJSONResponse(resp *http.Response) (bool, string) {
-	// Handle reuse of body stream
-	var buf bytes.Buffer
-	tee := io.TeeReader(resp.Body, &buf)
-	resp.Body = io.NopCloser(&buf)
+// maxResponseSize returns the effective response size limit, or -1 for no limit.
+func (c *Client) maxResponseSize() int64 {
+	switch {
+	case c.MaxResponseSize == 0:
+		return DefaultMaxResponseSize
+	case c.MaxResponseSize < 0:
+		return -1
+	default:
+		return c.MaxResponseSize
+	}
+}
 
-	bodyBytes, _ := io.ReadAll(tee)
-	// Body is empty, no need to check Content-Type
-	if len(bodyBytes) == 0 {
-		return true, ""
+// encodeBody returns the JSON encoding of body, or nil when there is no body.
+func encodeBody(body any) ([]byte, error) {
+	switch b := body.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return b, nil
+	case json.RawMessage:
+		return b, nil
+	default:
+		return json.Marshal(body)
 	}
+}
 
-	contentType := resp.Header.Get("Content-Type")
-	if len(contentType) < len("application/json") || !strings.Contains(contentType, "application/json") {
-		return false, ""
+// listQuery returns the query parameters for a list request, adding DefaultPageSize when req
+// sets no page size.
+func (c *Client) listQuery(req QueryBuilder) url.Values {
+	q := buildQuery(req)
+	if c.DefaultPageSize > 0 && q.Get("pageSize") == "" {
+		if q == nil {
+			q = url.Values{}
+		}
+		q.Set("pageSize", strconv.Itoa(c.DefaultPageSize))
 	}
+	return q
+}
 
-	return true, ""
+// buildQuery returns the query parameters for req, which may be nil.
+func buildQuery(req QueryBuilder) url.Values {
+	if req == nil {
+		return nil
+	}
+	return req.BuildQuery()
 }
@@ -0,0 +1,83 @@
+// Code generated by isbgen from spec.yaml. DO NOT EDIT.
+
+package isbclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// specOperation is an API operation defined in the spec.
+type specOperation struct {
+	Method string
+	Path   string // path template, e.g. /leases/{leaseId}
+}
+
+// specOperations maps operation names to their definitions in the spec, for hand-written
+// and generated methods alike.
+var specOperations = map[string]specOperation{
+	"CreateLease":             {Method: "POST", Path: "/leases"},
+	"CreateLeaseTemplate":     {Method: "POST", Path: "/leaseTemplates"},
+	"DeleteLeaseTemplate":     {Method: "DELETE", Path: "/leaseTemplates/{leaseTemplateId}"},
+	"EjectAccount":            {Method: "POST", Path: "/accounts/{awsAccountId}/eject"},
+	"FreezeLease":             {Method: "POST", Path: "/leases/{leaseId}/freeze"},
+	"GetAccountByID":          {Method: "GET", Path: "/accounts/{awsAccountId}"},
+	"GetAccounts":             {Method: "GET", Path: "/accounts"},
+	"GetConfigurations":       {Method: "GET", Path: "/configurations"},
+	"GetLeaseByID":            {Method: "GET", Path: "/leases/{leaseId}"},
+	"GetLeaseTemplateByID":    {Method: "GET", Path: "/leaseTemplates/{leaseTemplateId}"},
+	"GetLeaseTemplates":       {Method: "GET", Path: "/leaseTemplates"},
+	"GetLeases":               {Method: "GET", Path: "/leases"},
+	"GetLoginStatus":          {Method: "GET", Path: "/auth/login/status"},
+	"GetUnregisteredAccounts": {Method: "GET", Path: "/accounts/unregistered"},
+	"RegisterAccount":         {Method: "POST", Path: "/accounts"},
+	"RetryCleanup":            {Method: "POST", Path: "/accounts/{awsAccountId}/retryCleanup"},
+	"ReviewLease":             {Method: "POST", Path: "/leases/{leaseId}/review"},
+	"TerminateLease":          {Method: "POST", Path: "/leases/{leaseId}/terminate"},
+	"UpdateLease":             {Method: "PATCH", Path: "/leases/{leaseId}"},
+	"UpdateLeaseTemplate":     {Method: "PUT", Path: "/leaseTemplates/{leaseTemplateId}"},
+}
+
+// GetAccountByID calls GET /accounts/{awsAccountId}: Get account by account id.
+func (c *Client) GetAccountByID(ctx context.Context, req *GetAccountByIDRequest) (*GetAccountByIDResponse, error) {
+	if req == nil || req.AwsAccountID == "" {
+		return nil, &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("AwsAccountID is required")}
+	}
+	call := apiCall{method: http.MethodGet, path: "/accounts/" + req.AwsAccountID}
+	return invoke(ctx, c, "GetAccountByID", req, call, func(ctx context.Context) (*GetAccountByIDResponse, error) {
+		data, err := do[Account](ctx, c, call)
+		if err != nil {
+			return nil, err
+		}
+		return &GetAccountByIDResponse{Account: data}, nil
+	})
+}
+
+// GetLoginStatus calls GET /auth/login/status: Get login status.
+func (c *Client) GetLoginStatus(ctx context.Context) (*GetLoginStatusResponse, error) {
+	call := apiCall{method: http.MethodGet, path: "/auth/login/status", raw: true}
+	return invoke(ctx, c, "GetLoginStatus", nil, call, func(ctx context.Context) (*GetLoginStatusResponse, error) {
+		data, err := do[GetLoginStatusResponse](ctx, c, call)
+		if err != nil {
+			return nil, err
+		}
+		return &data, nil
+	})
+}
+
+// CreateLeaseTemplate calls POST /leaseTemplates: Create new lease template.
+func (c *Client) CreateLeaseTemplate(ctx context.Context, req *CreateLeaseTemplateRequest) (*CreateLeaseTemplateResponse, error) {
+	if req == nil {
+		return nil, &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("request is required")}
+	}
+	defer c.templates.invalidate()
+	call := apiCall{method: http.MethodPost, path: "/leaseTemplates", body: req}
+	return invoke(ctx, c, "CreateLeaseTemplate", req, call, func(ctx context.Context) (*CreateLeaseTemplateResponse, error) {
+		data, err := do[LeaseTemplate](ctx, c, call)
+		if err != nil {
+			return nil, err
+		}
+		return &CreateLeaseTemplateResponse{LeaseTemplate: data}, nil
+	})
+}
@@ -0,0 +1,99 @@
+package isbclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSpecOperations_HaveMethods(t *testing.T) {
+	client := reflect.TypeOf(&Client{})
+	for name := range specOperations {
+		if _, ok := client.MethodByName(name); !ok {
+			t.Errorf("operation %s has no Client method", name)
+		}
+	}
+}
+
+func TestCreateLeaseTemplate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/leaseTemplates" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		var got map[string]any
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		if got["name"] != "tpl" || got["requiresApproval"] != false {
+			t.Errorf("unexpected body: %s", body)
+		}
+		if _, ok := got["maxSpend"]; ok {
+			t.Errorf("expected unset optional members to be omitted, got %s", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"status":"success","data":{"uuid":"tpl-1","name":"tpl"}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	resp, err := client.CreateLeaseTemplate(context.Background(), &CreateLeaseTemplateRequest{Name: "tpl", Description: "d"})
+	if err != nil {
+		t.Fatalf("CreateLeaseTemplate error: %v", err)
+	}
+	if resp.LeaseTemplate.UUID != "tpl-1" {
+		t.Errorf("expected template tpl-1, got %+v", resp.LeaseTemplate)
+	}
+
+	var reqErr *APIRequestError
+	if _, err := client.CreateLeaseTemplate(context.Background(), nil); !errors.As(err, &reqErr) || reqErr.Op != "param" {
+		t.Errorf("expected a param error for a nil request, got %v", err)
+	}
+}
+
+func TestGetAccountByID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/accounts/123456789012" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"awsAccountId":"123456789012","status":"Available"}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	resp, err := client.GetAccountByID(context.Background(), &GetAccountByIDRequest{AwsAccountID: "123456789012"})
+	if err != nil {
+		t.Fatalf("GetAccountByID error: %v", err)
+	}
+	if resp.Account.Status != AccountStatusAvailable {
+		t.Errorf("expected status %s, got %q", AccountStatusAvailable, resp.Account.Status)
+	}
+
+	var reqErr *APIRequestError
+	if _, err := client.GetAccountByID(context.Background(), &GetAccountByIDRequest{}); !errors.As(err, &reqErr) || reqErr.Op != "param" {
+		t.Errorf("expected a param error for a missing account ID, got %v", err)
+	}
+}
+
+func TestGetLoginStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"authenticated":true,"session":{"user":{"email":"someone@example.com"}}}`))
+	}))
+	defer server.Close()
+
+	resp, err := NewClient(server.URL, "token").GetLoginStatus(context.Background())
+	if err != nil {
+		t.Fatalf("GetLoginStatus error: %v", err)
+	}
+	if !resp.Authenticated || resp.Session["user"] == nil {
+		t.Errorf("expected the unenveloped body to be decoded, got %+v", resp)
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -43,6 +46,52 @@ func TestCreateLease(t *testing.T) {
 	}
 }
 
+func TestGetConfigurations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/configurations" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{
+			"termsOfService":"tos","maintenanceMode":true,
+			"leases":{"maxBudget":500,"maxLeasesPerUser":3},
+			"auth":{"idpSignInUrl":"https://idp.example.com/signin","webAppUrl":"https://isb.example.com","sessionDurationInMinutes":60},
+			"notification":{"emailFrom":"isb@example.com"}}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	cfg, err := client.GetConfigurations(context.Background())
+	if err != nil {
+		t.Fatalf("GetConfigurations error: %v", err)
+	}
+	if !cfg.MaintenanceMode || cfg.Leases.MaxBudget != 500 || cfg.Notification.EmailFrom != "isb@example.com" {
+		t.Errorf("unexpected configuration: %+v", cfg)
+	}
+	if cfg.Auth.IdpSignInUrl != "https://idp.example.com/signin" || cfg.Auth.WebAppUrl != "https://isb.example.com" || cfg.Auth.SessionDurationInMinutes != 60 {
+		t.Errorf("unexpected auth configuration: %+v", cfg.Auth)
+	}
+}
+
+// GetConfigurations used to discard request errors and dereference a nil response.
+func TestGetConfigurations_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"status":"error","message":"config table unavailable"}`))
+	}))
+	client := NewClient(server.URL, "token")
+	cfg, err := client.GetConfigurations(context.Background())
+	if _, ok := err.(*ServerError); !ok || cfg != nil {
+		t.Errorf("expected ServerError and no configuration, got %v, %T %v", cfg, err, err)
+	}
+
+	server.Close()
+	cfg, err = client.GetConfigurations(context.Background())
+	if _, ok := err.(*APIRequestError); !ok || cfg != nil {
+		t.Errorf("expected APIRequestError and no configuration for an unreachable server, got %v, %T %v", cfg, err, err)
+	}
+}
+
 func TestUpdateLease(t *testing.T) {
 	uuid := "lease123"
 	userEmail := "user@example.com"
@@ -187,6 +236,199 @@ func TestUpdateLeaseTemplate(t *testing.T) {
 	}
 }
 
+func TestGetLeaseTemplateByID(t *testing.T) {
+	tplID := "tpl123"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("expected GET, got %s", r.Method)
+		}
+		if r.URL.Path != "/leaseTemplates/"+tplID {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data":   LeaseTemplate{UUID: tplID, Name: "tpl", RequiresApproval: true},
+		})
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	resp, err := client.GetLeaseTemplateByID(context.Background(), &GetLeaseTemplateByIDRequest{LeaseTemplateID: tplID})
+	if err != nil {
+		t.Fatalf("GetLeaseTemplateByID error: %v", err)
+	}
+	if resp.LeaseTemplate.UUID != tplID || !resp.LeaseTemplate.RequiresApproval {
+		t.Errorf("unexpected template: %+v", resp.LeaseTemplate)
+	}
+}
+
+func TestPatchLeaseTemplate(t *testing.T) {
+	tplID := "tpl123"
+	stored := `{"uuid":"tpl123","name":"tpl","description":"old","requiresApproval":true,"createdBy":"admin@example.com","maxSpend":50,"costCenter":"cc-42","meta":{"lastEditTime":"2025-01-01T00:00:00Z"}}`
+	var putBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`{"status":"success","data":` + stored + `}`))
+		case http.MethodPut:
+			if err := json.NewDecoder(r.Body).Decode(&putBody); err != nil {
+				t.Errorf("failed to decode PUT body: %v", err)
+			}
+			_, _ = w.Write([]byte(`{"status":"success","data":` + stored + `}`))
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	_, err := client.PatchLeaseTemplate(context.Background(), tplID, func(tpl *LeaseTemplate) {
+		tpl.Description = "new"
+	})
+	if err != nil {
+		t.Fatalf("PatchLeaseTemplate error: %v", err)
+	}
+	if putBody["description"] != "new" {
+		t.Errorf("expected mutated description, got %v", putBody["description"])
+	}
+	if putBody["requiresApproval"] != true || putBody["createdBy"] != "admin@example.com" || putBody["maxSpend"] != float64(50) {
+		t.Errorf("expected untouched fields to be preserved, got %v", putBody)
+	}
+	if putBody["costCenter"] != "cc-42" {
+		t.Errorf("expected unknown field to be preserved, got %v", putBody)
+	}
+}
+
+func TestPatchLeaseTemplate_ConcurrentEdit(t *testing.T) {
+	gets := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("expected no write after a concurrent edit, got %s", r.Method)
+		}
+		gets++
+		lastEdit := "2025-01-01T00:00:00Z"
+		if gets > 1 {
+			lastEdit = "2025-01-02T00:00:00Z"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data":   LeaseTemplate{UUID: "tpl123", Meta: MetaData{LastEditTime: lastEdit}},
+		})
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	_, err := client.PatchLeaseTemplate(context.Background(), "tpl123", func(tpl *LeaseTemplate) {
+		tpl.Name = "renamed"
+	})
+	modErr, ok := err.(*LeaseTemplateModifiedError)
+	if !ok {
+		t.Fatalf("expected LeaseTemplateModifiedError, got %T %v", err, err)
+	}
+	if modErr.ActualLastEditTime != "2025-01-02T00:00:00Z" || modErr.ExpectedLastEditTime != "2025-01-01T00:00:00Z" {
+		t.Errorf("unexpected edit times: %+v", modErr)
+	}
+}
+
+func TestPatchLeaseTemplate_EditedAfterWrite(t *testing.T) {
+	gets := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEdit := "2025-01-01T00:00:00Z"
+		switch {
+		case r.Method == http.MethodPut:
+			lastEdit = "2025-01-02T00:00:00Z"
+		case gets >= 2:
+			// Someone else wrote right after the patch.
+			lastEdit = "2025-01-03T00:00:00Z"
+		}
+		if r.Method == http.MethodGet {
+			gets++
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data":   LeaseTemplate{UUID: "tpl123", Meta: MetaData{LastEditTime: lastEdit}},
+		})
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	resp, err := client.PatchLeaseTemplate(context.Background(), "tpl123", func(tpl *LeaseTemplate) {
+		tpl.Name = "renamed"
+	})
+	modErr, ok := err.(*LeaseTemplateModifiedError)
+	if !ok || !modErr.Written {
+		t.Fatalf("expected a LeaseTemplateModifiedError after the write, got %T %v", err, err)
+	}
+	if modErr.ExpectedLastEditTime != "2025-01-02T00:00:00Z" || modErr.ActualLastEditTime != "2025-01-03T00:00:00Z" {
+		t.Errorf("unexpected edit times: %+v", modErr)
+	}
+	if resp == nil || resp.LeaseTemplate.Meta.LastEditTime != "2025-01-02T00:00:00Z" {
+		t.Errorf("expected the written template to be returned, got %+v", resp)
+	}
+	if gets != 3 {
+		t.Errorf("expected 3 GETs, got %d", gets)
+	}
+}
+
+func TestPatchLeaseTemplate_BypassesCache(t *testing.T) {
+	var mu sync.Mutex
+	lastEdit, puts := "v1", 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPut {
+			puts++
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data":   LeaseTemplate{UUID: "tpl123", Meta: MetaData{LastEditTime: lastEdit}},
+		})
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	client.Use(NewCache(CacheOptions{}).Middleware())
+	edit := func(v string) {
+		mu.Lock()
+		defer mu.Unlock()
+		lastEdit = v
+	}
+
+	ctx := context.Background()
+	if _, err := client.GetLeaseTemplateByID(ctx, &GetLeaseTemplateByIDRequest{LeaseTemplateID: "tpl123"}); err != nil {
+		t.Fatal(err)
+	}
+	edit("v2")
+	_, err := client.PatchLeaseTemplate(ctx, "tpl123", func(tpl *LeaseTemplate) {
+		edit("v3")
+	})
+	modErr, ok := err.(*LeaseTemplateModifiedError)
+	if !ok || modErr.ExpectedLastEditTime != "v2" || modErr.ActualLastEditTime != "v3" {
+		t.Fatalf("expected the mid-patch edit to be detected, got %T %v", err, err)
+	}
+	if puts != 0 {
+		t.Errorf("expected no write, got %d PUTs", puts)
+	}
+}
+
+func TestCloneLeaseTemplate(t *testing.T) {
+	var tpl LeaseTemplate
+	if err := json.Unmarshal([]byte(`{"uuid":"tpl","budgetThresholds":[{"dollarsSpent":10}],"costCenter":"cc-42"}`), &tpl); err != nil {
+		t.Fatal(err)
+	}
+	clone, err := cloneLeaseTemplate(tpl)
+	if err != nil {
+		t.Fatalf("cloneLeaseTemplate error: %v", err)
+	}
+	clone.BudgetThresholds[0].DollarsSpent = 99
+	if tpl.BudgetThresholds[0].DollarsSpent != 10 {
+		t.Error("expected the clone not to share slices with the original")
+	}
+	if string(clone.UnknownFields()["costCenter"]) != `"cc-42"` {
+		t.Errorf("expected unknown fields to be copied, got %v", clone.UnknownFields())
+	}
+}
+
 func TestDeleteLeaseTemplate(t *testing.T) {
 	tplID := "tpl123"
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -476,12 +718,58 @@ func TestFetchAllLeases(t *testing.T) {
 	}
 }
 
+func TestDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("expected GET, got %s", r.Method)
+		}
+		if r.URL.Path != "/accounts/unregistered" || r.URL.Query().Get("pageSize") != "5" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("expected bearer token, got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"result":[{"Id":"123456789012","Status":"ACTIVE"}],"nextPageIdentifier":""}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	var out struct {
+		Result []UnregisteredAccount `json:"result"`
+	}
+	err := client.Do(context.Background(), http.MethodGet, "/accounts/unregistered", url.Values{"pageSize": {"5"}}, nil, &out)
+	if err != nil {
+		t.Fatalf("Do error: %v", err)
+	}
+	if len(out.Result) != 1 || out.Result[0].Id != "123456789012" {
+		t.Errorf("unexpected result: %+v", out)
+	}
+}
+
+func TestDo_TypedErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"status":"fail","data":{"errors":[{"message":"invalid"}]}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	err := client.Do(context.Background(), http.MethodPost, "/accounts", nil, map[string]string{"awsAccountId": "bad"}, nil)
+	badReq, ok := err.(*BadRequestError)
+	if !ok {
+		t.Fatalf("expected BadRequestError, got %T %v", err, err)
+	}
+	if badReq.RequestBody != `{"awsAccountId":"bad"}` || len(badReq.Errors) != 1 {
+		t.Errorf("unexpected error detail: %+v", badReq)
+	}
+}
+
 func TestNonJSONResponses(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping slow non-JSON response tests in short mode")
 	}
 
-	t.Run("doGet returns error for HTML response", func(t *testing.T) {
+	t.Run("Do GET returns error for HTML response", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "text/html")
 			w.WriteHeader(http.StatusInternalServerError)
@@ -489,13 +777,13 @@ func TestNonJSONResponses(t *testing.T) {
 		}))
 		defer ts.Close()
 		client := NewClient(ts.URL, "token")
-		_, err := client.doGet(context.Background(), ts.URL)
+		err := client.Do(context.Background(), http.MethodGet, "/", nil, nil, nil)
 		if err == nil || err.Error() == "" || !contains(err.Error(), "non-JSON response") {
 			t.Errorf("expected non-JSON response error, got %v", err)
 		}
 	})
 
-	t.Run("doPost returns error for plain text response", func(t *testing.T) {
+	t.Run("Do POST returns error for plain text response", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "text/plain")
 			w.WriteHeader(http.StatusBadRequest)
@@ -503,13 +791,13 @@ func TestNonJSONResponses(t *testing.T) {
 		}))
 		defer ts.Close()
 		client := NewClient(ts.URL, "token")
-		_, err := client.doPost(context.Background(), ts.URL, []byte(`{}`))
+		err := client.Do(context.Background(), http.MethodPost, "/", nil, []byte(`{}`), nil)
 		if err == nil || err.Error() == "" || !contains(err.Error(), "non-JSON response") {
 			t.Errorf("expected non-JSON response error, got %v", err)
 		}
 	})
 
-	t.Run("doPatch returns error for XML response", func(t *testing.T) {
+	t.Run("Do PATCH returns error for XML response", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/xml")
 			w.WriteHeader(http.StatusForbidden)
@@ -517,13 +805,13 @@ func TestNonJSONResponses(t *testing.T) {
 		}))
 		defer ts.Close()
 		client := NewClient(ts.URL, "token")
-		_, err := client.doPatch(context.Background(), ts.URL, []byte(`{}`))
+		err := client.Do(context.Background(), http.MethodPatch, "/", nil, []byte(`{}`), nil)
 		if err == nil || err.Error() == "" || !contains(err.Error(), "non-JSON response") {
 			t.Errorf("expected non-JSON response error, got %v", err)
 		}
 	})
 
-	t.Run("doPut returns error for octet-stream response", func(t *testing.T) {
+	t.Run("Do PUT returns error for octet-stream response", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/octet-stream")
 			w.WriteHeader(http.StatusConflict)
@@ -531,13 +819,13 @@ func TestNonJSONResponses(t *testing.T) {
 		}))
 		defer ts.Close()
 		client := NewClient(ts.URL, "token")
-		_, err := client.doPut(context.Background(), ts.URL, []byte(`{}`))
+		err := client.Do(context.Background(), http.MethodPut, "/", nil, []byte(`{}`), nil)
 		if err == nil || err.Error() == "" || !contains(err.Error(), "non-JSON response") {
 			t.Errorf("expected non-JSON response error, got %v", err)
 		}
 	})
 
-	t.Run("doDelete returns error for XHTML response", func(t *testing.T) {
+	t.Run("Do DELETE returns error for XHTML response", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/xml")
 			w.WriteHeader(http.StatusForbidden)
@@ -545,7 +833,7 @@ func TestNonJSONResponses(t *testing.T) {
 		}))
 		defer ts.Close()
 		client := NewClient(ts.URL, "token")
-		_, err := client.doDelete(context.Background(), ts.URL)
+		err := client.Do(context.Background(), http.MethodDelete, "/", nil, nil, nil)
 		if err == nil || err.Error() == "" || !contains(err.Error(), "non-JSON response") {
 			t.Errorf("expected non-JSON response error, got %v", err)
 		}
@@ -553,5 +841,5 @@ func TestNonJSONResponses(t *testing.T) {
 }
 
 func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || (len(s) > len(substr) && (contains(s[1:], substr) || contains(s[:len(s)-1], substr))))
+	return strings.Contains(s, substr)
 }
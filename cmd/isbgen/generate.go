@@ -0,0 +1,700 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// operationNames names the operations of the spec, which has no operationIds, after the
+// client methods implementing them. Operations not listed get a name derived from their
+// method and path.
+var operationNames = map[string]string{
+	"GET /leases":                                "GetLeases",
+	"POST /leases":                               "CreateLease",
+	"GET /leases/{leaseId}":                      "GetLeaseByID",
+	"PATCH /leases/{leaseId}":                    "UpdateLease",
+	"POST /leases/{leaseId}/review":              "ReviewLease",
+	"POST /leases/{leaseId}/freeze":              "FreezeLease",
+	"POST /leases/{leaseId}/terminate":           "TerminateLease",
+	"GET /leaseTemplates":                        "GetLeaseTemplates",
+	"POST /leaseTemplates":                       "CreateLeaseTemplate",
+	"GET /leaseTemplates/{leaseTemplateId}":      "GetLeaseTemplateByID",
+	"PUT /leaseTemplates/{leaseTemplateId}":      "UpdateLeaseTemplate",
+	"DELETE /leaseTemplates/{leaseTemplateId}":   "DeleteLeaseTemplate",
+	"GET /accounts":                              "GetAccounts",
+	"POST /accounts":                             "RegisterAccount",
+	"GET /accounts/{awsAccountId}":               "GetAccountByID",
+	"POST /accounts/{awsAccountId}/retryCleanup": "RetryCleanup",
+	"POST /accounts/{awsAccountId}/eject":        "EjectAccount",
+	"GET /accounts/unregistered":                 "GetUnregisteredAccounts",
+	"GET /configurations":                        "GetConfigurations",
+	"GET /auth/login/status":                     "GetLoginStatus",
+}
+
+// typeNames maps schemas to the Go types modelling them where the names differ.
+var typeNames = map[string]string{
+	"PaginatedResults": "Page",
+}
+
+// itemTypeNames names the element types of array schemas.
+var itemTypeNames = map[string]string{
+	"BudgetThresholds":   "BudgetThreshold",
+	"DurationThresholds": "DurationThreshold",
+}
+
+// envelopeSchemas are handled by the request pipeline rather than generated.
+var envelopeSchemas = map[string]bool{
+	"ResponseBody":        true,
+	"SuccessResponseBody": true,
+	"FailResponseBody":    true,
+	"ErrorResponseBody":   true,
+	"PaginatedResults":    true,
+}
+
+const (
+	successResponseRef = "#/components/schemas/SuccessResponseBody"
+	paginatedRef       = "#/components/schemas/PaginatedResults"
+)
+
+type generator struct {
+	spec *spec
+
+	// Declarations written by hand: top-level identifiers, Client methods and the JSON
+	// member names of structs.
+	declared map[string]bool
+	methods  map[string]bool
+	structs  map[string][]string
+
+	enums      []string
+	decls      []string
+	stubs      []string
+	operations map[string][2]string
+	generated  map[string]bool
+	docs       map[string]string // doc comments of inline types, after their name
+	enumSets   map[string]bool
+	imports    map[string]map[string]bool
+	warnings   []string
+}
+
+// generate returns the generated files for the package in dir, keyed by file name.
+func generate(specPath, dir, pkg string) (map[string][]byte, []string, error) {
+	s, err := loadSpec(specPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	g := &generator{
+		spec:       s,
+		declared:   map[string]bool{},
+		methods:    map[string]bool{},
+		structs:    map[string][]string{},
+		operations: map[string][2]string{},
+		generated:  map[string]bool{},
+		docs:       map[string]string{},
+		enumSets:   map[string]bool{},
+		imports:    map[string]map[string]bool{"types": {}, "client": {}},
+	}
+	if err := g.scanPackage(dir); err != nil {
+		return nil, nil, err
+	}
+
+	for _, p := range s.Components.Schemas {
+		if !envelopeSchemas[p.Name] && len(p.Schema.Enum) > 0 {
+			g.enum(typeName(p.Name), p.Schema.Description, p.Schema.Enum)
+		}
+	}
+	for _, p := range s.Components.Schemas {
+		if envelopeSchemas[p.Name] {
+			continue
+		}
+		g.propertyEnums(typeName(p.Name), p.Schema)
+		if isObject(p.Schema) {
+			g.typeExpr(&schema{Ref: "#/components/schemas/" + p.Name}, "")
+		}
+	}
+	g.generateOperations()
+
+	header := fmt.Sprintf("// Code generated by isbgen from %s. DO NOT EDIT.\n\npackage %s\n\n", filepath.Base(specPath), pkg)
+	files := map[string][]byte{}
+	for name, parts := range map[string][][]string{
+		"types_gen.go":  {g.enums, g.decls},
+		"client_gen.go": {{g.operationTable()}, g.stubs},
+	} {
+		var b bytes.Buffer
+		b.WriteString(header)
+		writeImports(&b, g.imports[strings.TrimSuffix(name, "_gen.go")])
+		for _, part := range parts {
+			for _, decl := range part {
+				b.WriteString(decl)
+				b.WriteString("\n")
+			}
+		}
+		src, err := format.Source(b.Bytes())
+		if err != nil {
+			return nil, g.warnings, fmt.Errorf("format %s: %w\n%s", name, err, b.Bytes())
+		}
+		files[name] = src
+	}
+	return files, g.warnings, nil
+}
+
+// scanPackage records the declarations of the non-generated, non-test files in dir.
+func (g *generator) scanPackage(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		if ast.IsGenerated(f) {
+			continue
+		}
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					g.declared[d.Name.Name] = true
+				} else if receiverType(d.Recv.List[0].Type) == "Client" {
+					g.methods[d.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						g.declared[s.Name.Name] = true
+						if st, ok := s.Type.(*ast.StructType); ok {
+							g.structs[s.Name.Name] = jsonNames(st)
+						}
+					case *ast.ValueSpec:
+						for _, name := range s.Names {
+							g.declared[name.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func receiverType(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// jsonNames returns the JSON member names of the fields of st.
+func jsonNames(st *ast.StructType) []string {
+	var names []string
+	for _, field := range st.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			continue
+		}
+		if name, _, _ := strings.Cut(reflect.StructTag(tag).Get("json"), ","); name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// enum emits the constants of an enum unless its values were emitted already.
+func (g *generator) enum(prefix, description string, values []string) {
+	key := strings.Join(values, "\x00")
+	if g.enumSets[key] {
+		return
+	}
+	g.enumSets[key] = true
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s values", prefix)
+	if description != "" {
+		fmt.Fprintf(&b, ": %s", lowerFirst(strings.TrimSuffix(description, ".")))
+	}
+	b.WriteString(".\nconst (\n")
+	n := 0
+	for _, v := range values {
+		name := prefix + goName(v)
+		if g.declared[name] {
+			continue
+		}
+		fmt.Fprintf(&b, "\t%s = %q\n", name, v)
+		n++
+	}
+	b.WriteString(")\n")
+	if n > 0 {
+		g.enums = append(g.enums, b.String())
+	}
+}
+
+// propertyEnums emits the enums declared inline in the properties of sch.
+func (g *generator) propertyEnums(prefix string, sch *schema) {
+	if sch.Type == "array" && sch.Items != nil && sch.Items.Ref == "" {
+		g.propertyEnums(prefix, sch.Items)
+		return
+	}
+	for _, p := range sch.Properties {
+		if p.Schema.Ref != "" {
+			continue
+		}
+		if len(p.Schema.Enum) > 0 {
+			g.enum(prefix+goName(p.Name), p.Schema.Description, p.Schema.Enum)
+		}
+		g.propertyEnums(prefix+goName(p.Name), p.Schema)
+	}
+}
+
+func isObject(s *schema) bool {
+	return s.Type == "object" || len(s.Properties) > 0 || len(s.AllOf) > 0
+}
+
+// typeExpr returns the Go type for sch, generating the struct types it needs. Inline object
+// schemas are named hint.
+func (g *generator) typeExpr(sch *schema, hint string) string {
+	if sch == nil {
+		return "any"
+	}
+	if sch.Ref != "" {
+		name := refName(sch.Ref)
+		target := g.spec.schema(sch)
+		switch {
+		case target == nil:
+			return "any"
+		case isObject(target):
+			g.structType(typeName(name), target, target.Description)
+			return typeName(name)
+		case target.Type == "array":
+			return "[]" + g.typeExpr(target.Items, itemTypeName(name))
+		default:
+			return g.typeExpr(target, hint)
+		}
+	}
+	if len(sch.AllOf) == 1 && len(sch.Properties) == 0 {
+		return g.typeExpr(sch.AllOf[0], hint)
+	}
+	if isObject(sch) {
+		if props, _ := g.spec.objectProperties(sch); len(props) == 0 {
+			return "map[string]any"
+		}
+		g.structType(hint, sch, sch.Description)
+		return hint
+	}
+	switch sch.Type {
+	case "string":
+		return "string"
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.typeExpr(sch.Items, hint+"Item")
+	}
+	return "any"
+}
+
+// structType generates a struct for an object schema, unless it is declared by hand, in
+// which case spec properties it lacks are reported.
+func (g *generator) structType(name string, sch *schema, doc string) {
+	props, required := g.spec.objectProperties(sch)
+	if g.declared[name] {
+		if members, ok := g.structs[name]; ok && !g.generated[name] {
+			g.generated[name] = true
+			for _, p := range props {
+				if !slices.Contains(members, p.Name) {
+					g.warn("%s: spec property %q is not modelled", name, p.Name)
+				}
+			}
+		}
+		return
+	}
+	if g.generated[name] {
+		return
+	}
+	g.generated[name] = true
+	idx := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	var b strings.Builder
+	if doc == "" {
+		doc = g.docs[name]
+	}
+	if doc == "" {
+		doc = "is generated from the spec."
+	} else if !strings.HasPrefix(doc, "is ") {
+		doc = "is " + lowerFirst(strings.TrimSuffix(doc, ".")) + "."
+	}
+	fmt.Fprintf(&b, "// %s %s\ntype %s struct {\n", name, doc, name)
+	for _, p := range props {
+		field := goName(p.Name)
+		typ := g.typeExpr(p.Schema, name+field)
+		fmt.Fprintf(&b, "\t%s %s %s\n", field, typ, jsonTag(p.Name, slices.Contains(required, p.Name)))
+	}
+	b.WriteString("}\n")
+	g.decls[idx] = b.String()
+}
+
+func jsonTag(name string, required bool) string {
+	if required {
+		return fmt.Sprintf("`json:%q`", name)
+	}
+	return fmt.Sprintf("`json:%q`", name+",omitempty")
+}
+
+func (g *generator) warn(format string, args ...any) {
+	g.warnings = append(g.warnings, fmt.Sprintf(format, args...))
+}
+
+// generateOperations records every JSON API operation and generates stubs for those
+// without a hand-written method. Operations without a JSON response, such as the browser
+// login redirects, are not callable through the client and are left out.
+func (g *generator) generateOperations() {
+	paths := make([]string, 0, len(g.spec.Paths))
+	for path := range g.spec.Paths {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		item := g.spec.Paths[path]
+		for _, o := range item.operations() {
+			respSchema := g.successSchema(o.op)
+			if respSchema == nil {
+				continue
+			}
+			name := operationName(o.method, path)
+			g.operations[name] = [2]string{o.method, path}
+			if !g.methods[name] {
+				g.stub(name, o.method, path, item, o.op, respSchema)
+			}
+		}
+	}
+}
+
+// successSchema returns the schema of the first 2xx JSON response of op.
+func (g *generator) successSchema(op *operation) *schema {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+		if resp := g.spec.response(op.Responses[code]); resp != nil {
+			if mt := resp.Content["application/json"]; mt != nil && mt.Schema != nil {
+				return mt.Schema
+			}
+		}
+	}
+	return nil
+}
+
+func operationName(method, path string) string {
+	if name, ok := operationNames[method+" "+path]; ok {
+		return name
+	}
+	name := goName(strings.ToLower(method))
+	for _, seg := range strings.Split(strings.Trim(path, "/"), "/") {
+		if strings.HasPrefix(seg, "{") {
+			name += "By" + goName(strings.Trim(seg, "{}"))
+		} else {
+			name += goName(seg)
+		}
+	}
+	return name
+}
+
+// stub generates the request struct, response type and method of an operation.
+func (g *generator) stub(name, method, path string, item *pathItem, op *operation, respSchema *schema) {
+	var pathParams, queryParams []*parameter
+	for _, p := range append(slices.Clone(item.Parameters), op.Parameters...) {
+		switch p = g.spec.parameter(p); p.In {
+		case "path":
+			pathParams = append(pathParams, p)
+		case "query":
+			queryParams = append(queryParams, p)
+		}
+	}
+	var bodyProps properties
+	var bodyRequired []string
+	if op.RequestBody != nil {
+		if mt := op.RequestBody.Content["application/json"]; mt != nil {
+			bodyProps, bodyRequired = g.spec.objectProperties(mt.Schema)
+		}
+	}
+	reqType := name + "Request"
+	hasReq := len(pathParams)+len(queryParams)+len(bodyProps) > 0
+	if hasReq && g.declared[reqType] {
+		g.warn("%s: %s is declared by hand but %s is not; no method generated", name, reqType, name)
+		return
+	}
+
+	// Work out what the response decodes into and what the method returns.
+	respType := name + "Response"
+	g.docs[respType] = fmt.Sprintf("is the response of %s.\n// %s %s", name, method, path)
+	data, enveloped := g.responseData(respSchema)
+	var decodeType, result, wrapper string
+	switch {
+	case data == nil:
+	case slices.ContainsFunc(data.AllOf, func(s *schema) bool { return s.Ref == paginatedRef }):
+		props, _ := g.spec.objectProperties(data)
+		var items *schema
+		if res := props.get("result"); res != nil {
+			items = res.Items
+		}
+		decodeType = "Page[" + g.typeExpr(items, name+"Item") + "]"
+		respType, result = decodeType, "&data"
+	default:
+		if g.declared[respType] && !g.generated[respType] {
+			g.warn("%s: %s is declared by hand but %s is not; no method generated", name, respType, name)
+			return
+		}
+		decodeType = g.typeExpr(data, respType)
+		if decodeType == respType {
+			result = "&data"
+			break
+		}
+		field := decodeType
+		if !token.IsIdentifier(field) {
+			field = "Data"
+		}
+		wrapper = fmt.Sprintf("// %s is the response of %s.\n// %s %s\ntype %s struct {\n\t%s %s `json:\"data\"`\n}\n",
+			respType, name, method, path, respType, field, decodeType)
+		result = fmt.Sprintf("&%s{%s: data}", respType, field)
+	}
+	if hasReq {
+		g.requestStruct(reqType, name, method, path, pathParams, queryParams, bodyProps, bodyRequired)
+	}
+	if wrapper != "" {
+		g.decls = append(g.decls, wrapper)
+	}
+
+	imports := g.imports["client"]
+	imports["context"] = true
+	imports["net/http"] = true
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s calls %s %s: %s.\n", name, method, path, strings.TrimSuffix(op.Summary, "."))
+	fmt.Fprintf(&b, "func (c *Client) %s(ctx context.Context", name)
+	reqArg := "nil"
+	if hasReq {
+		fmt.Fprintf(&b, ", req *%s", reqType)
+		reqArg = "req"
+	}
+	if data == nil {
+		b.WriteString(") error {\n")
+	} else {
+		fmt.Fprintf(&b, ") (*%s, error) {\n", respType)
+	}
+
+	failure := "return nil, "
+	if data == nil {
+		failure = "return "
+	}
+	switch {
+	case len(pathParams) > 0:
+		imports["fmt"] = true
+		conds, fields := []string{"req == nil"}, []string{}
+		for _, p := range pathParams {
+			conds = append(conds, fmt.Sprintf("req.%s == \"\"", goName(p.Name)))
+			fields = append(fields, goName(p.Name))
+		}
+		verb := "is"
+		if len(fields) > 1 {
+			verb = "are"
+		}
+		fmt.Fprintf(&b, "\tif %s {\n\t\t%s&APIRequestError{Op: \"param\", URL: \"\", Err: fmt.Errorf(\"%s %s required\")}\n\t}\n",
+			strings.Join(conds, " || "), failure, strings.Join(fields, " and "), verb)
+	case len(bodyProps) > 0:
+		imports["fmt"] = true
+		fmt.Fprintf(&b, "\tif req == nil {\n\t\t%s&APIRequestError{Op: \"param\", URL: \"\", Err: fmt.Errorf(\"request is required\")}\n\t}\n", failure)
+	}
+
+	// Lease template writes drop the templates cached for name resolution.
+	if method != "GET" && strings.HasPrefix(path, "/leaseTemplates") {
+		b.WriteString("\tdefer c.templates.invalidate()\n")
+	}
+	fmt.Fprintf(&b, "\tcall := apiCall{method: http.Method%s, path: %s", goName(strings.ToLower(method)), pathExpr(path))
+	if len(queryParams) > 0 {
+		b.WriteString(", query: buildQuery(req)")
+	}
+	if len(bodyProps) > 0 {
+		b.WriteString(", body: req")
+	}
+	if !enveloped {
+		b.WriteString(", raw: true")
+	}
+	b.WriteString("}\n")
+	if data == nil {
+		fmt.Fprintf(&b, "\treturn invokeNoData(ctx, c, %q, %s, call)\n}\n", name, reqArg)
+	} else {
+		fmt.Fprintf(&b, "\treturn invoke(ctx, c, %q, %s, call, func(ctx context.Context) (*%s, error) {\n", name, reqArg, respType)
+		fmt.Fprintf(&b, "\t\tdata, err := do[%s](ctx, c, call)\n\t\tif err != nil {\n\t\t\treturn nil, err\n\t\t}\n", decodeType)
+		fmt.Fprintf(&b, "\t\treturn %s, nil\n\t})\n}\n", result)
+	}
+	g.stubs = append(g.stubs, b.String())
+}
+
+// responseData returns the schema of the data member of an enveloped response, nil when
+// it carries none, or the whole schema of a response that is not enveloped.
+func (g *generator) responseData(sch *schema) (data *schema, enveloped bool) {
+	if !slices.ContainsFunc(sch.AllOf, func(s *schema) bool { return s.Ref == successResponseRef }) {
+		return sch, false
+	}
+	props, _ := g.spec.objectProperties(sch)
+	data = props.get("data")
+	// SuccessResponseBody declares data as a bare object; only an override describes it.
+	if data != nil && data.Ref == "" && len(data.AllOf) == 0 && len(data.Properties) == 0 {
+		data = nil
+	}
+	return data, true
+}
+
+func (g *generator) requestStruct(reqType, name, method, path string, pathParams, queryParams []*parameter, bodyProps properties, bodyRequired []string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s is the request of %s.\n// %s %s\ntype %s struct {\n", reqType, name, method, path, reqType)
+	tag := ""
+	if len(bodyProps) > 0 {
+		tag = " `json:\"-\"`"
+	}
+	for _, p := range append(slices.Clone(pathParams), queryParams...) {
+		fmt.Fprintf(&b, "\t%s string%s\n", goName(p.Name), tag)
+	}
+	for _, p := range bodyProps {
+		field := goName(p.Name)
+		fmt.Fprintf(&b, "\t%s %s %s\n", field, g.typeExpr(p.Schema, reqType+field), jsonTag(p.Name, slices.Contains(bodyRequired, p.Name)))
+	}
+	b.WriteString("}\n")
+
+	if len(queryParams) > 0 {
+		g.imports["types"]["net/url"] = true
+		for _, p := range queryParams {
+			if p.Name == "pageIdentifier" {
+				fmt.Fprintf(&b, "\nfunc (r *%s) SetPageIdentifier(next string) {\n\tr.PageIdentifier = next\n}\n", reqType)
+			}
+		}
+		fmt.Fprintf(&b, "\nfunc (r *%s) BuildQuery() url.Values {\n\tif r == nil {\n\t\treturn url.Values{}\n\t}\n\tq := url.Values{}\n", reqType)
+		for _, p := range queryParams {
+			fmt.Fprintf(&b, "\tif r.%s != \"\" {\n\t\tq.Set(%q, r.%[1]s)\n\t}\n", goName(p.Name), p.Name)
+		}
+		b.WriteString("\treturn q\n}\n")
+	}
+	g.decls = append(g.decls, b.String())
+}
+
+// pathExpr returns a Go expression building path from the request's path parameters.
+func pathExpr(path string) string {
+	var parts []string
+	for path != "" {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			parts = append(parts, strconv.Quote(path))
+			break
+		}
+		end := strings.Index(path, "}")
+		if start > 0 {
+			parts = append(parts, strconv.Quote(path[:start]))
+		}
+		parts = append(parts, "req."+goName(path[start+1:end]))
+		path = path[end+1:]
+	}
+	return strings.Join(parts, " + ")
+}
+
+func (g *generator) operationTable() string {
+	names := make([]string, 0, len(g.operations))
+	for name := range g.operations {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var b strings.Builder
+	b.WriteString("// specOperation is an API operation defined in the spec.\ntype specOperation struct {\n\tMethod string\n\tPath   string // path template, e.g. /leases/{leaseId}\n}\n\n")
+	b.WriteString("// specOperations maps operation names to their definitions in the spec, for hand-written\n// and generated methods alike.\nvar specOperations = map[string]specOperation{\n")
+	for _, name := range names {
+		op := g.operations[name]
+		fmt.Fprintf(&b, "\t%q: {Method: %q, Path: %q},\n", name, op[0], op[1])
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func writeImports(b *bytes.Buffer, imports map[string]bool) {
+	if len(imports) == 0 {
+		return
+	}
+	paths := make([]string, 0, len(imports))
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	b.WriteString("import (\n")
+	for _, path := range paths {
+		fmt.Fprintf(b, "\t%q\n", path)
+	}
+	b.WriteString(")\n\n")
+}
+
+func typeName(schemaName string) string {
+	if name, ok := typeNames[schemaName]; ok {
+		return name
+	}
+	return goName(schemaName)
+}
+
+func itemTypeName(schemaName string) string {
+	if name, ok := itemTypeNames[schemaName]; ok {
+		return name
+	}
+	return typeName(schemaName) + "Item"
+}
+
+// goName converts a spec name or enum value to an exported Go identifier: words are
+// capitalised, SHOUTING words title-cased, and Id and Uuid suffixes written as initialisms.
+func goName(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	var b strings.Builder
+	for _, w := range words {
+		if strings.ToUpper(w) == w {
+			w = strings.ToLower(w)
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	name := b.String()
+	for _, initialism := range []string{"Id", "Uuid", "Url"} {
+		if strings.HasSuffix(name, initialism) {
+			return strings.TrimSuffix(name, initialism) + strings.ToUpper(initialism)
+		}
+	}
+	return name
+}
+
+func lowerFirst(s string) string {
+	if s == "" || (len(s) > 1 && unicode.IsUpper(rune(s[1]))) {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
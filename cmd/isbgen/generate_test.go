@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	files, _, err := generate("../../spec.yaml", "../..", "isbclient")
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join("../..", name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date with spec.yaml; run make generate", name)
+		}
+	}
+}
+
+const testSpec = `
+components:
+  schemas:
+    SuccessResponseBody:
+      type: object
+      properties:
+        status:
+          type: string
+        data:
+          type: object
+    Colour:
+      type: string
+      enum: [LIGHT_RED, Blue]
+    Widget:
+      type: object
+      required: [id]
+      properties:
+        id:
+          type: string
+        colour:
+          $ref: "#/components/schemas/Colour"
+        size:
+          type: integer
+    Gadget:
+      type: object
+      properties:
+        name:
+          type: string
+        mode:
+          type: string
+          enum: [on, off]
+paths:
+  /widgets/{widgetId}:
+    parameters:
+      - name: widgetId
+        in: path
+        required: true
+        schema:
+          type: string
+    get:
+      summary: Get widget
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                allOf:
+                  - $ref: "#/components/schemas/SuccessResponseBody"
+                  - type: object
+                    properties:
+                      data:
+                        $ref: "#/components/schemas/Widget"
+    post:
+      summary: Update widget
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                allOf:
+                  - $ref: "#/components/schemas/SuccessResponseBody"
+  /gadgets:
+    get:
+      summary: List gadgets
+      parameters:
+        - name: pageIdentifier
+          in: query
+          schema:
+            type: string
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                allOf:
+                  - $ref: "#/components/schemas/SuccessResponseBody"
+                  - type: object
+                    properties:
+                      data:
+                        allOf:
+                          - $ref: "#/components/schemas/PaginatedResults"
+                          - type: object
+                            properties:
+                              result:
+                                type: array
+                                items:
+                                  $ref: "#/components/schemas/Gadget"
+  /login:
+    get:
+      summary: Redirect to login
+      responses:
+        "302":
+          description: Redirect
+`
+
+const handWritten = `package widgets
+
+// Widget is written by hand and lacks the size member.
+type Widget struct {
+	ID     string ` + "`json:\"id\"`" + `
+	Colour string ` + "`json:\"colour\"`" + `
+}
+
+const ColourBlue = "Blue"
+
+type Client struct{}
+
+func (c *Client) PostWidgetsByWidgetID() error { return nil }
+`
+
+func TestGenerate_HandWrittenWins(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "spec.yaml"), []byte(testSpec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "widgets.go"), []byte(handWritten), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, warnings, err := generate(filepath.Join(dir, "spec.yaml"), dir, "widgets")
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	types, client := string(files["types_gen.go"]), string(files["client_gen.go"])
+
+	for _, want := range []string{
+		"// Code generated by isbgen from spec.yaml. DO NOT EDIT.",
+		`ColourLightRed = "LIGHT_RED"`,
+		`GadgetModeOn  = "on"`,
+		"type Gadget struct",
+		"type GetWidgetsByWidgetIDRequest struct",
+		"type GetWidgetsByWidgetIDResponse struct",
+	} {
+		if !strings.Contains(types, want) {
+			t.Errorf("types_gen.go: expected %q in\n%s", want, types)
+		}
+	}
+	for _, unwanted := range []string{"type Widget struct", "ColourBlue"} {
+		if strings.Contains(types, unwanted) {
+			t.Errorf("types_gen.go: expected hand-written %q to be left out", unwanted)
+		}
+	}
+
+	for _, want := range []string{
+		"func (c *Client) GetWidgetsByWidgetID(ctx context.Context, req *GetWidgetsByWidgetIDRequest) (*GetWidgetsByWidgetIDResponse, error)",
+		`req.WidgetID == ""`,
+		`"/widgets/" + req.WidgetID`,
+		"func (c *Client) GetGadgets(ctx context.Context, req *GetGadgetsRequest) (*Page[Gadget], error)",
+		`"PostWidgetsByWidgetID": {Method: "POST", Path: "/widgets/{widgetId}"}`,
+	} {
+		if !strings.Contains(client, want) {
+			t.Errorf("client_gen.go: expected %q in\n%s", want, client)
+		}
+	}
+	if strings.Contains(client, "func (c *Client) PostWidgetsByWidgetID") {
+		t.Error("client_gen.go: expected the hand-written method to be left out")
+	}
+	if strings.Contains(client, "/login") {
+		t.Error("client_gen.go: expected the redirect-only operation to be left out")
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `Widget: spec property "size" is not modelled`) {
+		t.Errorf("expected a drift warning for Widget.size, got %q", warnings)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"leaseId":         "LeaseID",
+		"awsAccountId":    "AwsAccountID",
+		"uuid":            "UUID",
+		"FREEZE_ACCOUNT":  "FreezeAccount",
+		"PendingApproval": "PendingApproval",
+		"retryCleanup":    "RetryCleanup",
+		"on":              "On",
+	}
+	for in, want := range tests {
+		if got := goName(in); got != want {
+			t.Errorf("goName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
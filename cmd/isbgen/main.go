@@ -0,0 +1,42 @@
+// Command isbgen generates the spec-derived parts of the isbclient package from spec.yaml:
+// enum constants, the types and request structs of operations the package does not
+// implement by hand, method stubs for those operations, and the table of API operations.
+//
+// Declarations written by hand always win: anything already declared in a non-generated
+// file of the package is left out of the generated files, so hand-written helpers can
+// replace generated code one declaration at a time. Spec properties missing from
+// hand-written types are reported as warnings.
+//
+// Run it from the package directory, or with make generate:
+//
+//	go run ./cmd/isbgen
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	specPath := flag.String("spec", "spec.yaml", "OpenAPI spec to generate from")
+	dir := flag.String("dir", ".", "package directory to generate into")
+	pkg := flag.String("pkg", "isbclient", "package name of the generated files")
+	flag.Parse()
+
+	files, warnings, err := generate(*specPath, *dir, *pkg)
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, "isbgen: warning:", w)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "isbgen:", err)
+		os.Exit(1)
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(*dir, name), src, 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, "isbgen:", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// spec is the subset of an OpenAPI 3.0 document the generator uses.
+type spec struct {
+	Paths      map[string]*pathItem `yaml:"paths"`
+	Components struct {
+		Schemas    properties            `yaml:"schemas"`
+		Parameters map[string]*parameter `yaml:"parameters"`
+		Responses  map[string]*response  `yaml:"responses"`
+	} `yaml:"components"`
+}
+
+type pathItem struct {
+	Parameters []*parameter `yaml:"parameters"`
+	Get        *operation   `yaml:"get"`
+	Post       *operation   `yaml:"post"`
+	Put        *operation   `yaml:"put"`
+	Patch      *operation   `yaml:"patch"`
+	Delete     *operation   `yaml:"delete"`
+}
+
+// operations returns the operations of the path item by HTTP method, in a fixed order.
+func (p *pathItem) operations() []struct {
+	method string
+	op     *operation
+} {
+	all := []struct {
+		method string
+		op     *operation
+	}{{"GET", p.Get}, {"POST", p.Post}, {"PUT", p.Put}, {"PATCH", p.Patch}, {"DELETE", p.Delete}}
+	out := all[:0]
+	for _, o := range all {
+		if o.op != nil {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+type operation struct {
+	Summary     string               `yaml:"summary"`
+	Parameters  []*parameter         `yaml:"parameters"`
+	RequestBody *requestBody         `yaml:"requestBody"`
+	Responses   map[string]*response `yaml:"responses"`
+}
+
+type parameter struct {
+	Ref         string  `yaml:"$ref"`
+	Name        string  `yaml:"name"`
+	In          string  `yaml:"in"`
+	Required    bool    `yaml:"required"`
+	Description string  `yaml:"description"`
+	Schema      *schema `yaml:"schema"`
+}
+
+type requestBody struct {
+	Content map[string]*mediaType `yaml:"content"`
+}
+
+type response struct {
+	Ref     string                `yaml:"$ref"`
+	Content map[string]*mediaType `yaml:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `yaml:"schema"`
+}
+
+type schema struct {
+	Ref         string     `yaml:"$ref"`
+	Type        string     `yaml:"type"`
+	Format      string     `yaml:"format"`
+	Description string     `yaml:"description"`
+	Enum        []string   `yaml:"enum"`
+	Required    []string   `yaml:"required"`
+	Properties  properties `yaml:"properties"`
+	Items       *schema    `yaml:"items"`
+	AllOf       []*schema  `yaml:"allOf"`
+}
+
+func (s *schema) required(name string) bool {
+	for _, r := range s.Required {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// property is a named schema. properties keeps the order of the document.
+type property struct {
+	Name   string
+	Schema *schema
+}
+
+type properties []property
+
+func (p *properties) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping", n.Line)
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		s := new(schema)
+		if err := n.Content[i+1].Decode(s); err != nil {
+			return err
+		}
+		*p = append(*p, property{Name: n.Content[i].Value, Schema: s})
+	}
+	return nil
+}
+
+func (p properties) get(name string) *schema {
+	for _, prop := range p {
+		if prop.Name == name {
+			return prop.Schema
+		}
+	}
+	return nil
+}
+
+// loadSpec reads and parses the spec at path.
+func loadSpec(path string) (*spec, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := new(spec)
+	if err := yaml.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// refName returns the component name a $ref points to.
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// schema resolves a schema reference; other schemas are returned as they are.
+func (s *spec) schema(sch *schema) *schema {
+	for sch != nil && sch.Ref != "" {
+		sch = s.Components.Schemas.get(refName(sch.Ref))
+	}
+	return sch
+}
+
+func (s *spec) parameter(p *parameter) *parameter {
+	if p.Ref != "" {
+		return s.Components.Parameters[refName(p.Ref)]
+	}
+	return p
+}
+
+func (s *spec) response(r *response) *response {
+	if r != nil && r.Ref != "" {
+		return s.Components.Responses[refName(r.Ref)]
+	}
+	return r
+}
+
+// objectProperties returns the properties of an object schema, merging allOf parts.
+func (s *spec) objectProperties(sch *schema) (props properties, required []string) {
+	sch = s.schema(sch)
+	if sch == nil {
+		return nil, nil
+	}
+	for _, part := range sch.AllOf {
+		p, r := s.objectProperties(part)
+		props = mergeProperties(props, p)
+		required = append(required, r...)
+	}
+	return mergeProperties(props, sch.Properties), append(required, sch.Required...)
+}
+
+// mergeProperties adds b to a, b's schemas replacing a's of the same name.
+func mergeProperties(a, b properties) properties {
+	for _, prop := range b {
+		replaced := false
+		for i := range a {
+			if a[i].Name == prop.Name {
+				a[i].Schema, replaced = prop.Schema, true
+			}
+		}
+		if !replaced {
+			a = append(a, prop)
+		}
+	}
+	return a
+}
@@ -0,0 +1,79 @@
+package isbclient
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// ConfigChange is a difference between two global configurations, as reported by Diff.
+type ConfigChange struct {
+	Path string // dotted JSON path of the changed member, e.g. "leases.maxBudget"
+	Old  any    // value in the first configuration, nil if the member was added
+	New  any    // value in the second configuration, nil if the member was removed
+}
+
+func (c ConfigChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, formatConfigValue(c.Old), formatConfigValue(c.New))
+}
+
+// Diff returns the differences between two global configurations, sorted by path. Objects
+// are compared member by member, including members the client does not model; arrays and
+// other values are compared as a whole. Values are reported as decoded from JSON (float64,
+// string, bool, []any, map[string]any).
+func Diff(a, b GlobalConfiguration) []ConfigChange {
+	var changes []ConfigChange
+	diffJSON("", toJSONValue(a), toJSONValue(b), &changes)
+	slices.SortFunc(changes, func(x, y ConfigChange) int { return cmp.Compare(x.Path, y.Path) })
+	return changes
+}
+
+// toJSONValue returns v as decoded by encoding/json into an any.
+func toJSONValue(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out any
+	_ = json.Unmarshal(b, &out)
+	return out
+}
+
+func diffJSON(path string, a, b any, changes *[]ConfigChange) {
+	am, aIsObject := a.(map[string]any)
+	bm, bIsObject := b.(map[string]any)
+	if !aIsObject || !bIsObject {
+		if !reflect.DeepEqual(a, b) {
+			*changes = append(*changes, ConfigChange{Path: path, Old: a, New: b})
+		}
+		return
+	}
+	for key, av := range am {
+		diffJSON(joinConfigPath(path, key), av, bm[key], changes)
+	}
+	for key, bv := range bm {
+		if _, ok := am[key]; !ok {
+			*changes = append(*changes, ConfigChange{Path: joinConfigPath(path, key), New: bv})
+		}
+	}
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func formatConfigValue(v any) string {
+	if v == nil {
+		return "<unset>"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,50 @@
+package isbclient
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	var a, b GlobalConfiguration
+	if err := json.Unmarshal([]byte(`{
+		"termsOfService": "v1",
+		"maintenanceMode": false,
+		"leases": {"maxBudget": 100, "defaultBudgetThresholds": [50, 90], "maxLeasesPerUser": 3},
+		"auth": {"webAppUrl": "https://isb.example.com", "legacy": true},
+		"notification": {"emailFrom": "isb@example.com"}
+	}`), &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{
+		"termsOfService": "v1",
+		"maintenanceMode": true,
+		"leases": {"maxBudget": 250, "defaultBudgetThresholds": [50, 75, 90], "maxLeasesPerUser": 3},
+		"auth": {"webAppUrl": "https://isb.example.com", "sessionDurationInMinutes": 60},
+		"notification": {"emailFrom": "isb@example.com"},
+		"newSection": {"enabled": true}
+	}`), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`auth.legacy: true -> <unset>`,
+		`auth.sessionDurationInMinutes: <unset> -> 60`,
+		`leases.defaultBudgetThresholds: [50,90] -> [50,75,90]`,
+		`leases.maxBudget: 100 -> 250`,
+		`maintenanceMode: false -> true`,
+		`newSection: <unset> -> {"enabled":true}`,
+	}
+	changes := Diff(a, b)
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %v", len(want), changes)
+	}
+	for i, c := range changes {
+		if c.String() != want[i] {
+			t.Errorf("change %d: expected %q, got %q", i, want[i], c.String())
+		}
+	}
+	if changes := Diff(a, a); len(changes) != 0 {
+		t.Errorf("expected no changes between identical configurations, got %v", changes)
+	}
+}
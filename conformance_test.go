@@ -0,0 +1,209 @@
+package isbclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The conformance tests serve responses synthesised from the examples in spec.yaml and check
+// that the client decodes every member of them.
+
+// loadSpec parses spec.yaml into generic maps.
+func loadSpec(t *testing.T) map[string]any {
+	t.Helper()
+	b, err := os.ReadFile("spec.yaml")
+	if err != nil {
+		t.Fatalf("read spec: %v", err)
+	}
+	var spec map[string]any
+	if err := yaml.Unmarshal(b, &spec); err != nil {
+		t.Fatalf("parse spec: %v", err)
+	}
+	return spec
+}
+
+// specExample builds an example value for schema, resolving $ref and allOf and using each
+// schema's example, its first enum value, or a placeholder for its type and format.
+func specExample(spec, schema map[string]any) any {
+	if ref, ok := schema["$ref"].(string); ok {
+		return specExample(spec, lookupRef(spec, ref))
+	}
+	if parts, ok := schema["allOf"].([]any); ok {
+		var merged any
+		for _, part := range parts {
+			merged = mergeExamples(merged, specExample(spec, part.(map[string]any)))
+		}
+		return merged
+	}
+	if example, ok := schema["example"]; ok {
+		return example
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	switch schema["type"] {
+	case "object":
+		out := map[string]any{}
+		props, _ := schema["properties"].(map[string]any)
+		for name, prop := range props {
+			out[name] = specExample(spec, prop.(map[string]any))
+		}
+		return out
+	case "array":
+		items, ok := schema["items"].(map[string]any)
+		if !ok {
+			return []any{}
+		}
+		return []any{specExample(spec, items)}
+	case "integer":
+		return 7
+	case "number":
+		return 7.5
+	case "boolean":
+		return true
+	}
+	switch schema["format"] {
+	case "email":
+		return "someone@example.com"
+	case "date-time":
+		return "2025-02-03T04:05:06Z"
+	case "uuid":
+		return "0f0f0f0f-1234-5678-9abc-def012345678"
+	}
+	return "example"
+}
+
+func lookupRef(spec map[string]any, ref string) map[string]any {
+	var node any = spec
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		node = node.(map[string]any)[part]
+	}
+	return node.(map[string]any)
+}
+
+// mergeExamples merges object b into a, b taking precedence.
+func mergeExamples(a, b any) any {
+	am, aok := a.(map[string]any)
+	bm, bok := b.(map[string]any)
+	if !aok || !bok {
+		return b
+	}
+	out := map[string]any{}
+	for k, v := range am {
+		out[k] = v
+	}
+	for k, v := range bm {
+		out[k] = mergeExamples(out[k], v)
+	}
+	return out
+}
+
+// normalizeJSON round-trips v through JSON so values can be compared regardless of Go type.
+func normalizeJSON(t *testing.T, v any) any {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return out
+}
+
+func TestConformance_ListEndpoints(t *testing.T) {
+	spec := loadSpec(t)
+	paths := spec["paths"].(map[string]any)
+
+	tests := []struct {
+		path string
+		list func(*Client) (items any, next string, err error)
+	}{
+		{"/leases", func(c *Client) (any, string, error) {
+			resp, err := c.GetLeases(context.Background(), nil)
+			if err != nil {
+				return nil, "", err
+			}
+			return resp.Leases, resp.NextPageIdentifier, nil
+		}},
+		{"/leaseTemplates", func(c *Client) (any, string, error) {
+			resp, err := c.GetLeaseTemplates(context.Background(), nil)
+			if err != nil {
+				return nil, "", err
+			}
+			return resp.LeaseTemplates, resp.NextPageIdentifier, nil
+		}},
+		{"/accounts", func(c *Client) (any, string, error) {
+			resp, err := c.GetAccounts(context.Background(), nil)
+			if err != nil {
+				return nil, "", err
+			}
+			return resp.Accounts, resp.NextPageIdentifier, nil
+		}},
+		{"/accounts/unregistered", func(c *Client) (any, string, error) {
+			resp, err := c.GetUnregisteredAccounts(context.Background(), nil)
+			if err != nil {
+				return nil, "", err
+			}
+			return resp.UnregisteredAccounts, resp.NextPageIdentifier, nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			op := paths[tt.path].(map[string]any)["get"].(map[string]any)
+			content := op["responses"].(map[string]any)["200"].(map[string]any)["content"].(map[string]any)
+			schema := content["application/json"].(map[string]any)["schema"].(map[string]any)
+			body := specExample(spec, schema)
+			encoded, err := json.Marshal(body)
+			if err != nil {
+				t.Fatalf("encode example: %v", err)
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					t.Errorf("unexpected path: %s", r.URL.Path)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write(encoded)
+			}))
+			defer server.Close()
+
+			items, next, err := tt.list(NewClient(server.URL, "token"))
+			if err != nil {
+				t.Fatalf("list error: %v", err)
+			}
+			data := body.(map[string]any)["data"].(map[string]any)
+			if next != data["nextPageIdentifier"] {
+				t.Errorf("expected nextPageIdentifier %v, got %q", data["nextPageIdentifier"], next)
+			}
+			want := normalizeJSON(t, data["result"]).([]any)
+			got := normalizeJSON(t, items).([]any)
+			if len(got) != len(want) {
+				t.Fatalf("expected %d items, got %d", len(want), len(got))
+			}
+			for i := range want {
+				// Unknown members are written back on encoding, so check none were left unmodelled.
+				if u, ok := reflect.ValueOf(items).Index(i).Interface().(interface {
+					UnknownFields() map[string]json.RawMessage
+				}); ok && len(u.UnknownFields()) > 0 {
+					t.Errorf("item %d: members not modelled: %v", i, u.UnknownFields())
+				}
+				wantItem, gotItem := want[i].(map[string]any), got[i].(map[string]any)
+				for k, v := range wantItem {
+					if !reflect.DeepEqual(gotItem[k], v) {
+						t.Errorf("item %d: member %q: expected %v, got %v", i, k, v, gotItem[k])
+					}
+				}
+			}
+		})
+	}
+}
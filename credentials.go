@@ -0,0 +1,360 @@
+package isbclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenEnvVar is the environment variable the credential chain reads a token from.
+const TokenEnvVar = "ISB_TOKEN"
+
+// ErrNoCredentials is returned by a CredentialChain when none of its sources has a token.
+var ErrNoCredentials = errors.New("no ISB credentials found: set a token, " + TokenEnvVar + ", a signing secret or a login function")
+
+// tokenExpiryMargin is how long before their expiry cached tokens stop being used.
+const tokenExpiryMargin = time.Minute
+
+// CachedToken is a token stored in a TokenCache.
+type CachedToken struct {
+	BaseURL   string    `json:"baseUrl"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// TokenCache stores tokens on disk, one entry per profile, so that CLIs do not need a new
+// token for every run. The file is only readable by its owner; Get refuses files other users
+// can access.
+type TokenCache struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewTokenCache returns a TokenCache using the file at path, or at DefaultTokenCachePath when
+// path is empty.
+func NewTokenCache(path string) *TokenCache {
+	return &TokenCache{path: path}
+}
+
+// DefaultTokenCachePath returns isb/tokens.json under the user's configuration directory, for
+// example ~/.config/isb/tokens.json on Linux.
+func DefaultTokenCachePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "isb", "tokens.json"), nil
+}
+
+type tokenCacheFile struct {
+	Profiles map[string]CachedToken `json:"profiles"`
+}
+
+func (c *TokenCache) filePath() (string, error) {
+	if c.path != "" {
+		return c.path, nil
+	}
+	return DefaultTokenCachePath()
+}
+
+func (c *TokenCache) load() (string, tokenCacheFile, error) {
+	file := tokenCacheFile{Profiles: map[string]CachedToken{}}
+	path, err := c.filePath()
+	if err != nil {
+		return "", file, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return path, file, nil
+	}
+	if err != nil {
+		return path, file, err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		return path, file, fmt.Errorf("token cache %s is accessible by other users (mode %04o); run chmod 600 %s", path, info.Mode().Perm(), path)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return path, file, err
+	}
+	if err := json.Unmarshal(b, &file); err != nil {
+		return path, file, fmt.Errorf("parse token cache %s: %w", path, err)
+	}
+	if file.Profiles == nil {
+		file.Profiles = map[string]CachedToken{}
+	}
+	return path, file, nil
+}
+
+func (c *TokenCache) save(path string, file tokenCacheFile) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tokens-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get returns the token cached for profile if it was issued for baseURL and does not expire
+// within the next minute.
+func (c *TokenCache) Get(profile, baseURL string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, file, err := c.load()
+	if err != nil {
+		return "", false, err
+	}
+	entry, ok := file.Profiles[profile]
+	if !ok || entry.BaseURL != baseURL || time.Until(entry.ExpiresAt) < tokenExpiryMargin {
+		return "", false, nil
+	}
+	return entry.Token, true, nil
+}
+
+// Put stores token for profile and baseURL. Its expiry is read from the token's exp claim;
+// tokens without one are not cached.
+func (c *TokenCache) Put(profile, baseURL, token string) error {
+	claims, err := ParseJWT(token)
+	if err != nil {
+		return err
+	}
+	if claims.ExpiresAt == nil {
+		return errors.New("token has no expiry and is not cached")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path, file, err := c.load()
+	if err != nil {
+		return err
+	}
+	file.Profiles[profile] = CachedToken{BaseURL: baseURL, Token: token, ExpiresAt: claims.ExpiresAt.Time}
+	return c.save(path, file)
+}
+
+// Delete removes the token cached for profile.
+func (c *TokenCache) Delete(profile string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path, file, err := c.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := file.Profiles[profile]; !ok {
+		return nil
+	}
+	delete(file.Profiles, profile)
+	return c.save(path, file)
+}
+
+// LoginFunc performs an interactive login and returns the token obtained.
+type LoginFunc func(ctx context.Context) (string, error)
+
+// PasteTokenLogin returns a LoginFunc asking the user to sign in to the ISB at baseURL in a
+// browser and paste the token into in. Prompts are written to out.
+func PasteTokenLogin(baseURL string, in io.Reader, out io.Writer) LoginFunc {
+	return func(context.Context) (string, error) {
+		fmt.Fprintf(out, "Sign in at %s/auth/login and paste your token: ", strings.TrimSuffix(baseURL, "/"))
+		line, err := bufio.NewReader(in).ReadString('\n')
+		token := strings.TrimSpace(line)
+		if token == "" {
+			if err == nil {
+				err = errors.New("no token entered")
+			}
+			return "", err
+		}
+		return token, nil
+	}
+}
+
+// Credential sources reported by CredentialChain.Source.
+const (
+	CredentialSourceExplicit = "explicit"
+	CredentialSourceEnv      = "env"
+	CredentialSourceCache    = "cache"
+	CredentialSourceSecret   = "secret"
+	CredentialSourceLogin    = "login"
+)
+
+// CredentialChainOptions configures a CredentialChain. Sources left unset are skipped.
+type CredentialChainOptions struct {
+	// Token is used as is when set.
+	Token string
+
+	// BaseURL and Profile select the TokenCache entry; cached tokens issued for another base
+	// URL are ignored. Profile defaults to "default".
+	BaseURL string
+	Profile string
+	// Cache stores tokens obtained by Login. Defaults to a TokenCache at DefaultTokenCachePath;
+	// set DisableCache to not use one.
+	Cache        *TokenCache
+	DisableCache bool
+
+	// Secret signs tokens for User with SecretTokenSource.
+	Secret    SecretProvider
+	User      UserClaims
+	ExpiresIn time.Duration
+
+	// Login is called when no other source has a token, for example PasteTokenLogin.
+	Login LoginFunc
+}
+
+// CredentialChain is a TokenRefresher trying, in order: the explicit token, the ISB_TOKEN
+// environment variable, the token cache, signing with a secret and interactive login. The
+// first source with a token is used until the API rejects it.
+type CredentialChain struct {
+	opts   CredentialChainOptions
+	cache  *TokenCache
+	signer *SecretTokenSource
+	mu     sync.Mutex
+	token  string
+	source string
+}
+
+// NewCredentialChain returns a CredentialChain with the given sources.
+func NewCredentialChain(opts CredentialChainOptions) *CredentialChain {
+	if opts.Profile == "" {
+		opts.Profile = "default"
+	}
+	c := &CredentialChain{opts: opts, cache: opts.Cache}
+	if c.cache == nil && !opts.DisableCache {
+		c.cache = NewTokenCache("")
+	}
+	if opts.Secret != nil {
+		c.signer = NewSecretTokenSource(opts.Secret, SecretTokenOptions{User: opts.User, ExpiresIn: opts.ExpiresIn})
+	}
+	return c
+}
+
+// Source returns the source of the token last returned by Token, one of the CredentialSource
+// constants, or "" before the first call.
+func (c *CredentialChain) Source() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.source
+}
+
+// Token returns the token of the first source that has one.
+func (c *CredentialChain) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.source == CredentialSourceSecret {
+		// Signed tokens expire; the signer renews them.
+		return c.signer.Token(ctx)
+	}
+	if c.token != "" && !tokenExpiring(c.token) {
+		return c.token, nil
+	}
+	token, source, err := c.resolve(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.source = token, source
+	return token, nil
+}
+
+func (c *CredentialChain) resolve(ctx context.Context) (string, string, error) {
+	if c.opts.Token != "" {
+		return c.opts.Token, CredentialSourceExplicit, nil
+	}
+	if token := os.Getenv(TokenEnvVar); token != "" {
+		return token, CredentialSourceEnv, nil
+	}
+	var cacheErr error
+	if c.cache != nil {
+		token, ok, err := c.cache.Get(c.opts.Profile, c.opts.BaseURL)
+		if ok {
+			return token, CredentialSourceCache, nil
+		}
+		cacheErr = err
+	}
+	if c.signer != nil {
+		token, err := c.signer.Token(ctx)
+		if err != nil {
+			return "", "", fmt.Errorf("sign token: %w", err)
+		}
+		return token, CredentialSourceSecret, nil
+	}
+	if c.opts.Login != nil {
+		token, err := c.opts.Login(ctx)
+		if err != nil {
+			return "", "", fmt.Errorf("login: %w", err)
+		}
+		if c.cache != nil {
+			// A token that cannot be cached is still good for this process.
+			_ = c.cache.Put(c.opts.Profile, c.opts.BaseURL, token)
+		}
+		return token, CredentialSourceLogin, nil
+	}
+	if cacheErr != nil {
+		return "", "", errors.Join(ErrNoCredentials, cacheErr)
+	}
+	return "", "", ErrNoCredentials
+}
+
+// Refresh drops a rejected cached or login token, removing it from the cache, and reports
+// whether another source has a token. Signed tokens are refreshed by refetching the secret.
+// Explicit and environment tokens are not replaced. Authenticate calls it only when the API
+// rejects the token with a 401; since it may delete the cached token and start an interactive
+// login, other callers should do the same.
+func (c *CredentialChain) Refresh(ctx context.Context, rejected string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.source {
+	case CredentialSourceSecret:
+		return c.signer.Refresh(ctx, rejected)
+	case CredentialSourceCache, CredentialSourceLogin:
+		if rejected != c.token {
+			return true, nil
+		}
+		if c.cache != nil {
+			if err := c.cache.Delete(c.opts.Profile); err != nil {
+				return false, err
+			}
+		}
+		token, source, err := c.resolve(ctx)
+		if err != nil {
+			return false, err
+		}
+		c.token, c.source = token, source
+		return token != rejected, nil
+	}
+	return false, nil
+}
+
+// tokenExpiring reports whether token expires within tokenExpiryMargin. Tokens that cannot be
+// decoded or carry no expiry are assumed valid.
+func tokenExpiring(token string) bool {
+	claims, err := ParseJWT(token)
+	if err != nil || claims.ExpiresAt == nil {
+		return false
+	}
+	return time.Until(claims.ExpiresAt.Time) < tokenExpiryMargin
+}
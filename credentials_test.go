@@ -0,0 +1,206 @@
+package isbclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testToken(t *testing.T, email string, expiresIn time.Duration) string {
+	t.Helper()
+	token, err := GenerateJWT(NewUserUserClaims(email), "secret", expiresIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestTokenCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "isb", "tokens.json")
+	cache := NewTokenCache(path)
+	dev, prod := testToken(t, "dev@example.com", time.Hour), testToken(t, "prod@example.com", time.Hour)
+
+	if err := cache.Put("dev", "https://dev.example.com/api", dev); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+	if err := cache.Put("prod", "https://prod.example.com/api", prod); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+	if runtime.GOOS != "windows" {
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+			t.Errorf("expected mode 0600, got %v %v", info.Mode().Perm(), err)
+		}
+	}
+
+	if token, ok, err := cache.Get("dev", "https://dev.example.com/api"); err != nil || !ok || token != dev {
+		t.Errorf("expected the dev token, got %v %v", ok, err)
+	}
+	if token, ok, _ := cache.Get("prod", "https://prod.example.com/api"); !ok || token != prod {
+		t.Error("expected the prod token")
+	}
+	if _, ok, _ := cache.Get("dev", "https://prod.example.com/api"); ok {
+		t.Error("expected a token for another base URL to be ignored")
+	}
+
+	if err := cache.Put("dev", "https://dev.example.com/api", testToken(t, "dev@example.com", 30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := cache.Get("dev", "https://dev.example.com/api"); ok {
+		t.Error("expected a token about to expire to be ignored")
+	}
+
+	if err := cache.Delete("prod"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, ok, _ := cache.Get("prod", "https://prod.example.com/api"); ok {
+		t.Error("expected the deleted token to be gone")
+	}
+}
+
+func TestTokenCache_RefusesOpenPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	if err := os.WriteFile(path, []byte(`{"profiles":{}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := NewTokenCache(path).Get("default", ""); err == nil || !strings.Contains(err.Error(), "chmod 600") {
+		t.Errorf("expected a permissions error, got %v", err)
+	}
+}
+
+func TestCredentialChain_Order(t *testing.T) {
+	t.Setenv(TokenEnvVar, "")
+	cache := NewTokenCache(filepath.Join(t.TempDir(), "tokens.json"))
+	cached := testToken(t, "cached@example.com", time.Hour)
+	if err := cache.Put("dev", "https://dev.example.com/api", cached); err != nil {
+		t.Fatal(err)
+	}
+	secret := SecretProviderFunc(func(context.Context) (string, error) { return "secret", nil })
+	login := LoginFunc(func(context.Context) (string, error) { return testToken(t, "login@example.com", time.Hour), nil })
+
+	tests := []struct {
+		name string
+		env  string
+		opts CredentialChainOptions
+		want string
+	}{
+		{"explicit", "env-token", CredentialChainOptions{Token: "explicit-token", Cache: cache, Profile: "dev", BaseURL: "https://dev.example.com/api"}, CredentialSourceExplicit},
+		{"env", "env-token", CredentialChainOptions{Cache: cache, Profile: "dev", BaseURL: "https://dev.example.com/api"}, CredentialSourceEnv},
+		{"cache", "", CredentialChainOptions{Cache: cache, Profile: "dev", BaseURL: "https://dev.example.com/api", Secret: secret}, CredentialSourceCache},
+		{"secret", "", CredentialChainOptions{Cache: cache, Profile: "prod", Secret: secret, Login: login}, CredentialSourceSecret},
+		{"login", "", CredentialChainOptions{Cache: cache, Profile: "staging", BaseURL: "https://staging.example.com/api", Login: login}, CredentialSourceLogin},
+	}
+	for _, tt := range tests {
+		t.Setenv(TokenEnvVar, tt.env)
+		chain := NewCredentialChain(tt.opts)
+		if _, err := chain.Token(context.Background()); err != nil {
+			t.Fatalf("%s: Token error: %v", tt.name, err)
+		}
+		if chain.Source() != tt.want {
+			t.Errorf("%s: expected source %s, got %s", tt.name, tt.want, chain.Source())
+		}
+	}
+
+	// The login token was cached for its profile.
+	if _, ok, _ := cache.Get("staging", "https://staging.example.com/api"); !ok {
+		t.Error("expected the login token to be cached")
+	}
+
+	_, err := NewCredentialChain(CredentialChainOptions{DisableCache: true}).Token(context.Background())
+	if !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("expected ErrNoCredentials, got %v", err)
+	}
+}
+
+func TestCredentialChain_RefreshDropsRejectedCacheEntry(t *testing.T) {
+	t.Setenv(TokenEnvVar, "")
+	cache := NewTokenCache(filepath.Join(t.TempDir(), "tokens.json"))
+	stale := testToken(t, "stale@example.com", time.Hour)
+	if err := cache.Put("dev", "https://dev.example.com/api", stale); err != nil {
+		t.Fatal(err)
+	}
+	fresh := testToken(t, "fresh@example.com", 2*time.Hour)
+	chain := NewCredentialChain(CredentialChainOptions{
+		Cache:   cache,
+		Profile: "dev",
+		BaseURL: "https://dev.example.com/api",
+		Login:   func(context.Context) (string, error) { return fresh, nil },
+	})
+
+	if token, _ := chain.Token(context.Background()); token != stale {
+		t.Fatal("expected the cached token first")
+	}
+	if ok, err := chain.Refresh(context.Background(), stale); err != nil || !ok {
+		t.Fatalf("expected a new token, got %v %v", ok, err)
+	}
+	if token, _ := chain.Token(context.Background()); token != fresh || chain.Source() != CredentialSourceLogin {
+		t.Errorf("expected the login token, got source %s", chain.Source())
+	}
+	if token, ok, _ := cache.Get("dev", "https://dev.example.com/api"); !ok || token != fresh {
+		t.Error("expected the rejected entry to be replaced by the login token")
+	}
+
+	explicit := NewCredentialChain(CredentialChainOptions{Token: "explicit", DisableCache: true})
+	_, _ = explicit.Token(context.Background())
+	if ok, _ := explicit.Refresh(context.Background(), "explicit"); ok {
+		t.Error("expected an explicit token not to be replaced")
+	}
+}
+
+func TestCredentialChain_ForbiddenKeepsCachedToken(t *testing.T) {
+	t.Setenv(TokenEnvVar, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"status":"fail","data":{"errors":[{"message":"forbidden"}]}}`))
+	}))
+	defer server.Close()
+	cache := NewTokenCache(filepath.Join(t.TempDir(), "tokens.json"))
+	cached := testToken(t, "user@example.com", time.Hour)
+	if err := cache.Put("dev", server.URL, cached); err != nil {
+		t.Fatal(err)
+	}
+	var logins int
+	chain := NewCredentialChain(CredentialChainOptions{
+		Cache:   cache,
+		Profile: "dev",
+		BaseURL: server.URL,
+		Login: func(context.Context) (string, error) {
+			logins++
+			return "login-token", nil
+		},
+	})
+	client := NewClient(server.URL, "")
+	client.Use(Authenticate(chain))
+
+	var unauthorized *UnauthorizedError
+	if _, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"}); !errors.As(err, &unauthorized) {
+		t.Fatalf("expected UnauthorizedError, got %T %v", err, err)
+	}
+	if token, ok, _ := cache.Get("dev", server.URL); !ok || token != cached || logins != 0 {
+		t.Errorf("expected a 403 to keep the cached token without a login, got cached %t, %d logins", ok && token == cached, logins)
+	}
+}
+
+func TestPasteTokenLogin(t *testing.T) {
+	var out strings.Builder
+	token, err := PasteTokenLogin("https://isb.example.com/api/", strings.NewReader(" pasted-token \n"), &out)(context.Background())
+	if err != nil || token != "pasted-token" {
+		t.Errorf("expected pasted-token, got %q %v", token, err)
+	}
+	if !strings.Contains(out.String(), "https://isb.example.com/api/auth/login") {
+		t.Errorf("expected the login URL in the prompt, got %q", out.String())
+	}
+	if _, err := PasteTokenLogin("", strings.NewReader("\n"), &out)(context.Background()); err == nil {
+		t.Error("expected an error for an empty token")
+	}
+}
@@ -2,10 +2,14 @@ package isbclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // APIRequestError wraps errors related to making API requests.
@@ -148,16 +152,49 @@ func (e *LeaseNotFoundError) Error() string {
 }
 
 // LeaseTemplateNotFoundError represents a 404 Not Found error for a lease template resource.
+// It is also returned, without a status code, for template names that cannot be resolved;
+// Suggestions then lists close matches.
 type LeaseTemplateNotFoundError struct {
 	APIResponseError
-	Errors []FailErrorDetail
+	Errors      []FailErrorDetail
+	Suggestions []string
 }
 
 func (e *LeaseTemplateNotFoundError) Error() string {
+	msg := "lease template not found: " + e.Message
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" (status %d)", e.StatusCode)
+	}
 	if len(e.Errors) > 0 {
-		return fmt.Sprintf("lease template not found: %s (status %d) errors: %v", e.Message, e.StatusCode, e.Errors)
+		msg += fmt.Sprintf(" errors: %v", e.Errors)
 	}
-	return fmt.Sprintf("lease template not found: %s (status %d)", e.Message, e.StatusCode)
+	if len(e.Suggestions) > 0 {
+		msg += fmt.Sprintf("; did you mean %s?", quoteList(e.Suggestions))
+	}
+	return msg
+}
+
+// AmbiguousLeaseTemplateError is returned when a lease template name matches several templates.
+type AmbiguousLeaseTemplateError struct {
+	Name    string
+	Matches []LeaseTemplate
+}
+
+func (e *AmbiguousLeaseTemplateError) Error() string {
+	names := make([]string, len(e.Matches))
+	for i, tpl := range e.Matches {
+		names[i] = fmt.Sprintf("%q (%s)", tpl.Name, tpl.UUID)
+	}
+	return fmt.Sprintf("lease template name %q is ambiguous: matches %s", e.Name, strings.Join(names, ", "))
+}
+
+// quoteList returns the quoted names separated by commas.
+func quoteList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.Quote(name)
+	}
+	return strings.Join(quoted, ", ")
 }
 
 // AccountNotFoundError represents a 404 Not Found error for an account resource.
@@ -212,120 +249,403 @@ func (e *AccountConflictError) Error() string {
 	return fmt.Sprintf("account conflict: %s (status %d)", e.Message, e.StatusCode)
 }
 
+// LeaseTemplateModifiedError is returned by PatchLeaseTemplate when the template was edited by
+// someone else while it was being patched. Written reports whether the patch had already been
+// written back when the edit was detected.
+type LeaseTemplateModifiedError struct {
+	LeaseTemplateID      string
+	ExpectedLastEditTime string
+	ActualLastEditTime   string
+	Written              bool
+}
+
+func (e *LeaseTemplateModifiedError) Error() string {
+	if e.Written {
+		return fmt.Sprintf("lease template %s was modified concurrently after being written: last edit time %q, expected %q", e.LeaseTemplateID, e.ActualLastEditTime, e.ExpectedLastEditTime)
+	}
+	return fmt.Sprintf("lease template %s was modified concurrently: last edit time %q, expected %q", e.LeaseTemplateID, e.ActualLastEditTime, e.ExpectedLastEditTime)
+}
+
+// MaintenanceModeError is returned when the ISB is in maintenance mode and does not accept new
+// leases, either because the API rejected the request or because a MaintenancePreflight check
+// found maintenance mode enabled. Err holds the underlying API error, if any.
+type MaintenanceModeError struct {
+	Err error
+}
+
+func (e *MaintenanceModeError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("innovation sandbox is in maintenance mode: %v", e.Err)
+	}
+	return "innovation sandbox is in maintenance mode"
+}
+
+func (e *MaintenanceModeError) Unwrap() error {
+	return e.Err
+}
+
+// ResponseTooLargeError is returned when a response body exceeds the client's MaxResponseSize.
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds the %d byte limit", e.Limit)
+}
+
+// ResponseValidationError is returned by ValidateResponses in strict mode when a response body
+// does not match the schema spec.yaml declares for it.
+type ResponseValidationError struct {
+	Op         string // operation, e.g. "GetLeases"
+	URL        string // request path
+	Violations []SchemaViolation
+	Err        error // the *JSONDecodingError, if the body could not be decoded either
+}
+
+func (e *ResponseValidationError) Error() string {
+	if len(e.Violations) == 0 {
+		return fmt.Sprintf("response of %s %s violates the spec", e.Op, e.URL)
+	}
+	msg := fmt.Sprintf("response of %s %s violates the spec: %s", e.Op, e.URL, e.Violations[0])
+	if len(e.Violations) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Violations)-1)
+	}
+	return msg
+}
+
+func (e *ResponseValidationError) Unwrap() error {
+	return e.Err
+}
+
+// LeaseNotActiveError is returned by AcquireLease when a requested lease is denied or ends
+// before it becomes Active.
+type LeaseNotActiveError struct {
+	LeaseID string
+	Status  string
+}
+
+func (e *LeaseNotActiveError) Error() string {
+	return fmt.Sprintf("lease %s is %s and did not become active", e.LeaseID, e.Status)
+}
+
+// ForbiddenForRoleError is returned by RolePreflight for a call the roles of the client's
+// token may not perform, before the request is sent.
+type ForbiddenForRoleError struct {
+	Op      string   // operation, e.g. "ReviewLease"
+	Roles   []string // roles the token carries
+	Allowed []string // roles allowed to perform Op
+}
+
+func (e *ForbiddenForRoleError) Error() string {
+	return fmt.Sprintf("%s is not permitted for roles %v; requires one of %v", e.Op, e.Roles, e.Allowed)
+}
+
+// InvalidTokenError is returned by ParseJWT and VerifyJWT for a token that is malformed, not
+// yet valid or issued for another issuer or audience.
+type InvalidTokenError struct {
+	Err error
+}
+
+func (e *InvalidTokenError) Error() string {
+	return fmt.Sprintf("invalid token: %v", e.Err)
+}
+
+func (e *InvalidTokenError) Unwrap() error {
+	return e.Err
+}
+
+// TokenExpiredError is returned by VerifyJWT for a token whose expiry has passed.
+type TokenExpiredError struct {
+	ExpiredAt time.Time
+}
+
+func (e *TokenExpiredError) Error() string {
+	return fmt.Sprintf("token expired at %s", e.ExpiredAt.Format(time.RFC3339))
+}
+
+// TokenSignatureError is returned by VerifyJWT for a token whose signature does not verify
+// with the given secret or key, or whose algorithm does not match it.
+type TokenSignatureError struct {
+	Err error
+}
+
+func (e *TokenSignatureError) Error() string {
+	return fmt.Sprintf("token signature is invalid: %v", e.Err)
+}
+
+func (e *TokenSignatureError) Unwrap() error {
+	return e.Err
+}
+
+// MissingRolesError is returned by VerifyJWT for a token carrying none of the required roles.
+type MissingRolesError struct {
+	Required []string
+	Roles    []string // roles the token carries
+}
+
+func (e *MissingRolesError) Error() string {
+	return fmt.Sprintf("token has roles %v, requires one of %v", e.Roles, e.Required)
+}
+
+// ErrorCategory returns a short, stable name for the kind of err, suitable as a metric label
+// or span attribute: for example "lease_not_found", "unauthorized", "server_error",
+// "transport" or "timeout". It returns "" for a nil error and "other" for errors not
+// produced by this package.
+func ErrorCategory(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.Canceled) {
+		return "canceled"
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	var maintenance *MaintenanceModeError
+	if errors.As(err, &maintenance) {
+		return "maintenance_mode"
+	}
+	var forbidden *ForbiddenForRoleError
+	if errors.As(err, &forbidden) {
+		return "forbidden_for_role"
+	}
+	var notActive *LeaseNotActiveError
+	if errors.As(err, &notActive) {
+		return "lease_not_active"
+	}
+	var (
+		leaseNotFound         *LeaseNotFoundError
+		leaseTemplateNotFound *LeaseTemplateNotFoundError
+		accountNotFound       *AccountNotFoundError
+		notFound              *NotFoundError
+		leaseConflict         *LeaseConflictError
+		leaseTemplateConflict *LeaseTemplateConflictError
+		accountConflict       *AccountConflictError
+		conflict              *ConflictError
+		modified              *LeaseTemplateModifiedError
+		badRequest            *BadRequestError
+		unauthorized          *UnauthorizedError
+		server                *ServerError
+		fail                  *FailResponseError
+		tooLarge              *ResponseTooLargeError
+		decoding              *JSONDecodingError
+		validation            *ResponseValidationError
+		response              *APIResponseError
+		request               *APIRequestError
+	)
+	switch {
+	case errors.As(err, &leaseNotFound):
+		return "lease_not_found"
+	case errors.As(err, &leaseTemplateNotFound):
+		return "lease_template_not_found"
+	case errors.As(err, &accountNotFound):
+		return "account_not_found"
+	case errors.As(err, &notFound):
+		return "not_found"
+	case errors.As(err, &leaseConflict):
+		return "lease_conflict"
+	case errors.As(err, &leaseTemplateConflict):
+		return "lease_template_conflict"
+	case errors.As(err, &accountConflict):
+		return "account_conflict"
+	case errors.As(err, &conflict):
+		return "conflict"
+	case errors.As(err, &modified):
+		return "lease_template_modified"
+	case errors.As(err, &badRequest):
+		return "bad_request"
+	case errors.As(err, &unauthorized):
+		return "unauthorized"
+	case errors.As(err, &server):
+		return "server_error"
+	case errors.As(err, &fail):
+		return "fail"
+	case errors.As(err, &tooLarge):
+		return "response_too_large"
+	case errors.As(err, &validation):
+		return "response_validation"
+	case errors.As(err, &decoding):
+		return "json_decoding"
+	case errors.As(err, &response):
+		return "response"
+	case errors.As(err, &request):
+		if request.Op == "do" {
+			return "transport"
+		}
+		return "request"
+	}
+	return "other"
+}
+
 // DecodeAPIError decodes the API error response and returns the appropriate error type.
 func DecodeAPIError(reqBody []byte, resp *http.Response) error {
 	defer resp.Body.Close()
 
-	// Read the body into a buffer so we can decode multiple times
 	b := new(bytes.Buffer)
 	_, _ = b.ReadFrom(resp.Body)
-	bodyBytes := b.Bytes()
+	_, err := decodeAPIError(reqBody, resp, b.Bytes())
+	return err
+}
+
+// errorEnvelope is the JSend envelope of an error response.
+type errorEnvelope struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Code    int             `json:"code,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// decodeAPIError maps an error response body onto the appropriate error type, wrapping it in a
+// *MaintenanceModeError when the API refused to create a lease because of maintenance mode. It
+// also returns the envelope status of the body, if it could be parsed.
+func decodeAPIError(reqBody []byte, resp *http.Response, bodyBytes []byte) (string, error) {
+	var envelope errorEnvelope
+	parsed := json.Unmarshal(bodyBytes, &envelope) == nil
+	if !parsed {
+		envelope = errorEnvelope{}
+	}
+	err := decodeErrorResponse(reqBody, resp, bodyBytes, envelope, parsed)
+	if refusedForMaintenance(resp, err) {
+		return envelope.Status, &MaintenanceModeError{Err: err}
+	}
+	return envelope.Status, err
+}
 
-	var (
-		failBody struct {
-			Status string `json:"status"`
-			Data   struct {
-				Errors []FailErrorDetail `json:"errors"`
-			} `json:"data"`
+// refusedForMaintenance reports whether err is the ISB's refusal of a lease request in
+// maintenance mode: a 400 fail response to POST /leases whose message mentions maintenance mode.
+func refusedForMaintenance(resp *http.Response, err error) bool {
+	req := resp.Request
+	if req == nil || req.URL == nil || req.Method != http.MethodPost || !strings.HasSuffix(strings.TrimSuffix(req.URL.Path, "/"), "/leases") {
+		return false
+	}
+	var badRequest *BadRequestError
+	if !errors.As(err, &badRequest) {
+		return false
+	}
+	for _, detail := range badRequest.Errors {
+		if strings.Contains(strings.ToLower(detail.Message), "maintenance mode") {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeErrorResponse maps an error response body onto the appropriate error type. The body is
+// parsed once, into envelope; its data member is then interpreted according to the envelope status.
+func decodeErrorResponse(reqBody []byte, resp *http.Response, bodyBytes []byte, envelope errorEnvelope, parsed bool) error {
+
+	// A fail body carries data.errors; an error body carries a message and optional data object.
+	var failErrors []FailErrorDetail
+	isFail := false
+	if parsed && envelope.Status == "fail" {
+		var data struct {
+			Errors []FailErrorDetail `json:"errors"`
 		}
-		errorBody struct {
-			Status  string                 `json:"status"`
-			Message string                 `json:"message"`
-			Code    int                    `json:"code,omitempty"`
-			Data    map[string]interface{} `json:"data,omitempty"`
+		if len(envelope.Data) == 0 || json.Unmarshal(envelope.Data, &data) == nil {
+			failErrors, isFail = data.Errors, true
 		}
-	)
+	}
+	var errorData map[string]interface{}
+	isError := false
+	if parsed && envelope.Status == "error" {
+		if len(envelope.Data) == 0 || json.Unmarshal(envelope.Data, &errorData) == nil {
+			isError = true
+		}
+	}
 
-	urlPath := resp.Request.URL.Path
+	urlPath := ""
+	if resp.Request != nil && resp.Request.URL != nil {
+		urlPath = resp.Request.URL.Path
+	}
 
 	switch resp.StatusCode {
 	case 400:
-		if err := json.NewDecoder(bytes.NewReader(bodyBytes)).Decode(&failBody); err == nil && failBody.Status == "fail" {
+		if isFail {
 			return &BadRequestError{
 				APIResponseError: APIResponseError{StatusCode: 400, Message: "bad request"},
-				Errors:           failBody.Data.Errors,
+				Errors:           failErrors,
 				RequestBody:      string(reqBody),
 			}
 		}
 	case 401, 403:
-		if err := json.NewDecoder(bytes.NewReader(bodyBytes)).Decode(&failBody); err == nil && failBody.Status == "fail" {
+		if isFail {
 			return &UnauthorizedError{
 				APIResponseError: APIResponseError{StatusCode: resp.StatusCode, Message: "unauthorized"},
 			}
 		}
 	case 404:
-		if err := json.NewDecoder(bytes.NewReader(bodyBytes)).Decode(&failBody); err == nil && failBody.Status == "fail" {
+		if isFail {
 			// Resource-specific not found errors
 			if strings.HasPrefix(urlPath, "/leases/") {
 				return &LeaseNotFoundError{
 					APIResponseError: APIResponseError{StatusCode: 404, Message: "lease not found"},
-					Errors:           failBody.Data.Errors,
+					Errors:           failErrors,
 				}
 			} else if strings.HasPrefix(urlPath, "/leaseTemplates/") {
 				return &LeaseTemplateNotFoundError{
 					APIResponseError: APIResponseError{StatusCode: 404, Message: "lease template not found"},
-					Errors:           failBody.Data.Errors,
+					Errors:           failErrors,
 				}
 			} else if strings.HasPrefix(urlPath, "/accounts/") {
 				return &AccountNotFoundError{
 					APIResponseError: APIResponseError{StatusCode: 404, Message: "account not found"},
-					Errors:           failBody.Data.Errors,
+					Errors:           failErrors,
 				}
 			}
 			// fallback
 			return &NotFoundError{
 				APIResponseError: APIResponseError{StatusCode: 404, Message: "not found"},
-				Errors:           failBody.Data.Errors,
+				Errors:           failErrors,
 			}
 		}
 	case 409:
-		if err := json.NewDecoder(bytes.NewReader(bodyBytes)).Decode(&failBody); err == nil && failBody.Status == "fail" {
+		if isFail {
 			// Resource-specific conflict errors
 			if strings.HasPrefix(urlPath, "/leases/") {
 				return &LeaseConflictError{
 					APIResponseError: APIResponseError{StatusCode: 409, Message: "lease conflict"},
-					Errors:           failBody.Data.Errors,
+					Errors:           failErrors,
 				}
 			} else if strings.HasPrefix(urlPath, "/leaseTemplates/") {
 				return &LeaseTemplateConflictError{
 					APIResponseError: APIResponseError{StatusCode: 409, Message: "lease template conflict"},
-					Errors:           failBody.Data.Errors,
+					Errors:           failErrors,
 				}
 			} else if strings.HasPrefix(urlPath, "/accounts/") {
 				return &AccountConflictError{
 					APIResponseError: APIResponseError{StatusCode: 409, Message: "account conflict"},
-					Errors:           failBody.Data.Errors,
+					Errors:           failErrors,
 				}
 			}
 			// fallback
 			return &ConflictError{
 				APIResponseError: APIResponseError{StatusCode: 409, Message: "conflict"},
-				Errors:           failBody.Data.Errors,
+				Errors:           failErrors,
 			}
 		}
 	case 500:
-		if err := json.NewDecoder(bytes.NewReader(bodyBytes)).Decode(&errorBody); err == nil && errorBody.Status == "error" {
+		if isError {
 			return &ServerError{
-				APIResponseError: APIResponseError{StatusCode: 500, Message: errorBody.Message},
-				Code:             errorBody.Code,
-				Data:             errorBody.Data,
+				APIResponseError: APIResponseError{StatusCode: 500, Message: envelope.Message},
+				Code:             envelope.Code,
+				Data:             errorData,
 			}
 		}
 	}
-	// fallback: try to decode as fail
-	if err := json.NewDecoder(bytes.NewReader(bodyBytes)).Decode(&failBody); err == nil && failBody.Status == "fail" {
+	// fallback: fail body
+	if isFail {
 		return &FailResponseError{
-			Status:     failBody.Status,
-			Errors:     failBody.Data.Errors,
+			Status:     envelope.Status,
+			Errors:     failErrors,
 			StatusCode: resp.StatusCode,
 		}
 	}
-	// fallback: try to decode as error
-	if err := json.NewDecoder(bytes.NewReader(bodyBytes)).Decode(&errorBody); err == nil && errorBody.Status == "error" {
+	// fallback: error body
+	if isError {
 		return &ServerError{
-			APIResponseError: APIResponseError{StatusCode: resp.StatusCode, Message: errorBody.Message},
-			Code:             errorBody.Code,
-			Data:             errorBody.Data,
+			APIResponseError: APIResponseError{StatusCode: resp.StatusCode, Message: envelope.Message},
+			Code:             envelope.Code,
+			Data:             errorData,
 		}
 	}
 	// fallback: generic error
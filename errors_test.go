@@ -2,7 +2,10 @@ package isbclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -99,3 +102,34 @@ func TestDecodeAPIError_Generic(t *testing.T) {
 		t.Errorf("expected APIResponseError with body, got %T %+v", err, err)
 	}
 }
+
+func TestErrorCategory(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{&LeaseNotFoundError{}, "lease_not_found"},
+		{&LeaseTemplateConflictError{}, "lease_template_conflict"},
+		{&NotFoundError{}, "not_found"},
+		{&BadRequestError{}, "bad_request"},
+		{&UnauthorizedError{}, "unauthorized"},
+		{&ServerError{}, "server_error"},
+		{&LeaseTemplateModifiedError{}, "lease_template_modified"},
+		{&ResponseTooLargeError{}, "response_too_large"},
+		{&ResponseValidationError{}, "response_validation"},
+		{&ForbiddenForRoleError{Op: "ReviewLease"}, "forbidden_for_role"},
+		{&LeaseNotActiveError{Status: "Expired"}, "lease_not_active"},
+		{&APIResponseError{StatusCode: 418}, "response"},
+		{&APIRequestError{Op: "do", Err: errors.New("connection refused")}, "transport"},
+		{&APIRequestError{Op: "do", Err: context.DeadlineExceeded}, "timeout"},
+		{&APIRequestError{Op: "marshal", Err: errors.New("bad value")}, "request"},
+		{fmt.Errorf("wrapped: %w", &AccountNotFoundError{}), "account_not_found"},
+		{errors.New("boom"), "other"},
+	}
+	for _, tt := range tests {
+		if got := ErrorCategory(tt.err); got != tt.want {
+			t.Errorf("ErrorCategory(%T) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -3,3 +3,5 @@ module github.com/gymshark/aws-go-isb-client
 go 1.24.5
 
 require github.com/golang-jwt/jwt/v5 v5.3.0
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,112 @@
+// Package isbtest provisions Innovation Sandbox leases for Go tests: Lease acquires a lease
+// for the duration of a test, and Server is an in-process fake of the ISB API to run such
+// tests without a deployment.
+package isbtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	isbclient "github.com/gymshark/aws-go-isb-client"
+)
+
+// DefaultReleaseTimeout bounds the termination of a lease when LeaseOptions.ReleaseTimeout is
+// zero.
+const DefaultReleaseTimeout = 30 * time.Second
+
+// LeaseOptions configures LeaseWithOptions. Zero fields take the documented defaults.
+type LeaseOptions struct {
+	// PollInterval is the delay between checks while the lease awaits approval (default 10s).
+	PollInterval time.Duration
+
+	// ReleaseTimeout bounds the termination of the lease (default DefaultReleaseTimeout). The
+	// lease is released this long before the test's deadline, so that it is not leaked when
+	// the test binary times out.
+	ReleaseTimeout time.Duration
+
+	// IgnoreSignals leaves SIGINT and SIGTERM alone. By default the lease is terminated when
+	// the test process receives either of them.
+	IgnoreSignals bool
+}
+
+// Lease acquires a lease of template, by name or UUID, for the rest of the test with the
+// default LeaseOptions. See LeaseWithOptions.
+func Lease(t testing.TB, client *isbclient.Client, template string) *isbclient.LeaseHandle {
+	t.Helper()
+	return LeaseWithOptions(t, client, template, LeaseOptions{})
+}
+
+// LeaseWithOptions acquires a lease of template, by name or UUID, for the rest of the test and
+// returns its handle, whose LeaseID and AccountID identify the lease and its AWS account.
+//
+// The lease is requested with the test's context, bounded by its deadline less
+// ReleaseTimeout. It is terminated when that context ends, that is when the test ends or nears
+// its deadline, including while awaiting approval, or on SIGINT or SIGTERM; a t.Cleanup
+// reports the outcome. The test is skipped when the ISB cannot be reached or is in maintenance
+// mode, and fails if the lease cannot be acquired or its deadline is less than ReleaseTimeout
+// away.
+func LeaseWithOptions(t testing.TB, client *isbclient.Client, template string, opts LeaseOptions) *isbclient.LeaseHandle {
+	t.Helper()
+	if opts.ReleaseTimeout <= 0 {
+		opts.ReleaseTimeout = DefaultReleaseTimeout
+	}
+	ctx := t.Context()
+	// testing.TB has no Deadline; *testing.T does.
+	if dt, ok := t.(interface{ Deadline() (time.Time, bool) }); ok {
+		if deadline, ok := dt.Deadline(); ok {
+			releaseBy := deadline.Add(-opts.ReleaseTimeout)
+			if !releaseBy.After(time.Now()) {
+				t.Fatalf("isbtest: the test deadline %s leaves less than ReleaseTimeout (%s) to acquire and terminate a lease; raise -timeout or lower ReleaseTimeout",
+					deadline.Format(time.RFC3339), opts.ReleaseTimeout)
+			}
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, releaseBy)
+			t.Cleanup(cancel)
+		}
+	}
+
+	cfg, err := client.GetConfigurations(ctx)
+	if err != nil {
+		if unreachable(err) && ctx.Err() == nil {
+			t.Skipf("isbtest: innovation sandbox is unreachable: %v", err)
+		}
+		t.Fatalf("isbtest: get configurations: %v", err)
+	}
+	if cfg.MaintenanceMode {
+		t.Skip("isbtest: innovation sandbox is in maintenance mode")
+	}
+
+	// AcquireLease terminates the lease itself if it fails or ctx ends once the lease exists.
+	h, err := client.AcquireLease(ctx, template, isbclient.AcquireLeaseOptions{
+		Comments:       "isbtest: " + t.Name(),
+		PollInterval:   opts.PollInterval,
+		HandleSignals:  !opts.IgnoreSignals,
+		ReleaseTimeout: opts.ReleaseTimeout,
+	})
+	if err != nil {
+		var maintenance *isbclient.MaintenanceModeError
+		if errors.As(err, &maintenance) || (unreachable(err) && ctx.Err() == nil) {
+			t.Skipf("isbtest: %v", err)
+		}
+		t.Fatalf("isbtest: acquire lease of %s: %v", template, err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.ReleaseTimeout)
+		defer cancel()
+		if err := h.Release(ctx); err != nil {
+			t.Errorf("isbtest: terminate lease %s: %v", h.LeaseID, err)
+			return
+		}
+		t.Logf("isbtest: terminated lease %s", h.LeaseID)
+	})
+	t.Logf("isbtest: lease %s of %s in AWS account %s", h.LeaseID, template, h.AccountID)
+	return h
+}
+
+// unreachable reports whether err is a failure to reach the API at all.
+func unreachable(err error) bool {
+	var reqErr *isbclient.APIRequestError
+	return errors.As(err, &reqErr) && reqErr.Op == "do"
+}
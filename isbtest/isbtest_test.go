@@ -0,0 +1,109 @@
+package isbtest
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	isbclient "github.com/gymshark/aws-go-isb-client"
+)
+
+func TestLease(t *testing.T) {
+	server := NewServer(t)
+	server.AddTemplate(isbclient.LeaseTemplate{Name: "Sandbox", LeaseDurationInHours: 4})
+	client := server.Client("dev@example.com")
+
+	var h *isbclient.LeaseHandle
+	t.Run("acquire", func(t *testing.T) {
+		h = Lease(t, client, "Sandbox")
+		if h.AccountID == "" || h.LeaseID == "" {
+			t.Errorf("expected an account and lease ID, got %+v", h)
+		}
+		if lease, _ := server.Lease(h.LeaseID); lease.Status != isbclient.LeaseStatusActive || lease.UserEmail != "dev@example.com" {
+			t.Errorf("expected an Active lease of dev@example.com, got %+v", lease)
+		}
+	})
+	if lease, _ := server.Lease(h.LeaseID); lease.Status != isbclient.LeaseStatusTerminated {
+		t.Errorf("expected the lease to be terminated after the test, got %s", lease.Status)
+	}
+}
+
+func TestLease_WaitsForApproval(t *testing.T) {
+	server := NewServer(t)
+	server.AddTemplate(isbclient.LeaseTemplate{Name: "Reviewed", RequiresApproval: true})
+	go func() {
+		for {
+			if leases := server.Leases(); len(leases) > 0 {
+				_ = server.Review(leases[0].LeaseId, isbclient.ReviewApprove)
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	t.Run("acquire", func(t *testing.T) {
+		opts := LeaseOptions{PollInterval: time.Millisecond, IgnoreSignals: true}
+		if h := LeaseWithOptions(t, server.Client(DefaultUser), "Reviewed", opts); h.AccountID == "" {
+			t.Error("expected an account once approved")
+		}
+	})
+}
+
+func TestLease_Skips(t *testing.T) {
+	server := NewServer(t)
+	server.AddTemplate(isbclient.LeaseTemplate{Name: "Sandbox"})
+	server.SetMaintenance(true)
+
+	closed := httptest.NewServer(nil)
+	closed.Close()
+
+	for name, client := range map[string]*isbclient.Client{
+		"maintenance": server.Client(DefaultUser),
+		"unreachable": isbclient.NewClient(closed.URL, ""),
+	} {
+		var sub *testing.T
+		t.Run(name, func(t *testing.T) {
+			sub = t
+			Lease(t, client, "Sandbox")
+			t.Error("expected the test to be skipped")
+		})
+		if !sub.Skipped() {
+			t.Errorf("%s: expected a skip", name)
+		}
+	}
+	if len(server.Leases()) != 0 {
+		t.Error("expected no lease to be requested")
+	}
+}
+
+// deadlineT overrides the deadline of a test and records, rather than reports, a fatal failure.
+type deadlineT struct {
+	*testing.T
+	deadline time.Time
+	fatal    string
+}
+
+func (t *deadlineT) Deadline() (time.Time, bool) { return t.deadline, true }
+
+func (t *deadlineT) Fatalf(format string, args ...any) {
+	t.fatal = fmt.Sprintf(format, args...)
+	t.SkipNow()
+}
+
+func TestLease_DeadlineWithinReleaseTimeout(t *testing.T) {
+	server := NewServer(t)
+	server.AddTemplate(isbclient.LeaseTemplate{Name: "Sandbox"})
+
+	dt := &deadlineT{deadline: time.Now().Add(time.Second)}
+	t.Run("acquire", func(t *testing.T) {
+		dt.T = t
+		LeaseWithOptions(dt, server.Client(DefaultUser), "Sandbox", LeaseOptions{ReleaseTimeout: time.Minute})
+	})
+	if !strings.Contains(dt.fatal, "ReleaseTimeout (1m0s)") || !strings.Contains(dt.fatal, "test deadline") {
+		t.Errorf("expected a failure naming the deadline and ReleaseTimeout, got %q", dt.fatal)
+	}
+	if len(server.Leases()) != 0 {
+		t.Error("expected no lease to be requested")
+	}
+}
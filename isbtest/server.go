@@ -0,0 +1,269 @@
+package isbtest
+
+import (
+	"crypto/rand"
+	b64 "encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	isbclient "github.com/gymshark/aws-go-isb-client"
+)
+
+// DefaultUser is the user of requests without a token.
+const DefaultUser = "test@example.com"
+
+// Server is an in-process fake of the Innovation Sandbox API. It serves the global
+// configuration, lease templates and the lease lifecycle: requesting, reviewing, polling and
+// terminating leases. Leases of templates that do not require approval are Active, with an
+// AWS account, as soon as they are requested. Tokens are decoded but not verified.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	maintenance bool
+	templates   []isbclient.LeaseTemplate
+	leases      []*isbclient.Lease
+	accounts    int
+}
+
+// NewServer starts a fake ISB that is closed when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Client returns a client for the fake, authenticated as email with the User role.
+func (s *Server) Client(email string) *isbclient.Client {
+	token, err := isbclient.GenerateJWT(isbclient.NewUserUserClaims(email), "isbtest", time.Hour)
+	if err != nil {
+		panic(err)
+	}
+	return isbclient.NewClient(s.URL, token)
+}
+
+// AddTemplate adds a lease template, assigning a UUID when it has none, and returns it.
+func (s *Server) AddTemplate(tpl isbclient.LeaseTemplate) isbclient.LeaseTemplate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tpl.UUID == "" {
+		tpl.UUID = newUUID()
+	}
+	s.templates = append(s.templates, tpl)
+	return tpl
+}
+
+// SetMaintenance turns maintenance mode on or off. In maintenance mode new leases are refused.
+func (s *Server) SetMaintenance(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maintenance = on
+}
+
+// Review approves or denies a lease awaiting approval, as POST /leases/{leaseId}/review does.
+func (s *Server) Review(leaseID, action string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lease := s.lease(leaseID)
+	if lease == nil {
+		return fmt.Errorf("lease %s not found", leaseID)
+	}
+	return s.review(lease, action)
+}
+
+// Lease returns the lease with the given ID.
+func (s *Server) Lease(leaseID string) (isbclient.Lease, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if lease := s.lease(leaseID); lease != nil {
+		return *lease, true
+	}
+	return isbclient.Lease{}, false
+}
+
+// Leases returns every lease requested from the fake, in order.
+func (s *Server) Leases() []isbclient.Lease {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	leases := make([]isbclient.Lease, len(s.leases))
+	for i, lease := range s.leases {
+		leases[i] = *lease
+	}
+	return leases
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	// Lease IDs are standard base64 and may contain slashes, so match actions by suffix.
+	leaseID, action := strings.TrimPrefix(path, "/leases/"), ""
+	for _, a := range []string{"review", "terminate"} {
+		if id, ok := strings.CutSuffix(leaseID, "/"+a); ok {
+			leaseID, action = id, a
+		}
+	}
+	switch {
+	case r.Method == http.MethodGet && path == "/configurations":
+		success(w, isbclient.GlobalConfiguration{MaintenanceMode: s.maintenance})
+	case r.Method == http.MethodGet && path == "/leaseTemplates":
+		success(w, map[string]any{"result": s.templates, "nextPageIdentifier": nil})
+	case r.Method == http.MethodGet && path == "/leases":
+		var leases []isbclient.Lease
+		for _, lease := range s.leases {
+			if email := r.URL.Query().Get("userEmail"); email == "" || lease.UserEmail == email {
+				leases = append(leases, *lease)
+			}
+		}
+		success(w, map[string]any{"result": leases, "nextPageIdentifier": nil})
+	case r.Method == http.MethodPost && path == "/leases":
+		s.createLease(w, r)
+	case !strings.HasPrefix(path, "/leases/"):
+		fail(w, http.StatusNotFound, "no route for %s %s", r.Method, path)
+	case s.lease(leaseID) == nil:
+		fail(w, http.StatusNotFound, "lease %s not found", leaseID)
+	case r.Method == http.MethodGet && action == "":
+		success(w, s.lease(leaseID))
+	case r.Method == http.MethodPost && action == "review":
+		var body struct {
+			Action string `json:"action"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if err := s.review(s.lease(leaseID), body.Action); err != nil {
+			fail(w, http.StatusConflict, "%v", err)
+			return
+		}
+		success(w, nil)
+	case r.Method == http.MethodPost && action == "terminate":
+		lease := s.lease(leaseID)
+		switch lease.Status {
+		case isbclient.LeaseStatusPendingApproval, isbclient.LeaseStatusActive, isbclient.LeaseStatusFrozen:
+			lease.Status = isbclient.LeaseStatusTerminated
+			lease.EndDate = time.Now().UTC().Format(time.RFC3339)
+			success(w, nil)
+		default:
+			fail(w, http.StatusConflict, "lease %s is %s", leaseID, lease.Status)
+		}
+	default:
+		fail(w, http.StatusNotFound, "no route for %s %s", r.Method, path)
+	}
+}
+
+func (s *Server) createLease(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		LeaseTemplateUUID string `json:"leaseTemplateUuid"`
+		Comments          string `json:"comments"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		fail(w, http.StatusBadRequest, "invalid body: %v", err)
+		return
+	}
+	if s.maintenance {
+		fail(w, http.StatusBadRequest, "Cannot create lease while Innovation Sandbox is in maintenance mode")
+		return
+	}
+	var tpl *isbclient.LeaseTemplate
+	for i := range s.templates {
+		if s.templates[i].UUID == body.LeaseTemplateUUID {
+			tpl = &s.templates[i]
+		}
+	}
+	if tpl == nil {
+		fail(w, http.StatusBadRequest, "lease template %s not found", body.LeaseTemplateUUID)
+		return
+	}
+
+	email := DefaultUser
+	if claims, err := isbclient.ParseJWT(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")); err == nil && claims.User.Email != "" {
+		email = claims.User.Email
+	}
+	lease := &isbclient.Lease{
+		UserEmail:                 email,
+		UUID:                      newUUID(),
+		Status:                    isbclient.LeaseStatusPendingApproval,
+		OriginalLeaseTemplateUuid: tpl.UUID,
+		OriginalLeaseTemplateName: tpl.Name,
+		LeaseDurationInHours:      tpl.LeaseDurationInHours,
+		MaxSpend:                  tpl.MaxSpend,
+		Comments:                  body.Comments,
+	}
+	ids, _ := json.Marshal(map[string]string{"userEmail": lease.UserEmail, "uuid": lease.UUID})
+	lease.LeaseId = b64.StdEncoding.EncodeToString(ids)
+	if !tpl.RequiresApproval {
+		s.activate(lease)
+	}
+	s.leases = append(s.leases, lease)
+	writeJSON(w, http.StatusCreated, map[string]any{"status": "success", "data": lease})
+}
+
+// review applies a review action to lease. The caller holds s.mu.
+func (s *Server) review(lease *isbclient.Lease, action string) error {
+	if lease.Status != isbclient.LeaseStatusPendingApproval {
+		return fmt.Errorf("lease %s is %s, not awaiting approval", lease.LeaseId, lease.Status)
+	}
+	switch action {
+	case isbclient.ReviewApprove:
+		s.activate(lease)
+	case isbclient.ReviewDeny:
+		lease.Status = isbclient.LeaseStatusTerminated
+		lease.EndDate = time.Now().UTC().Format(time.RFC3339)
+	default:
+		return fmt.Errorf("unknown review action %q", action)
+	}
+	return nil
+}
+
+// activate makes lease Active in a new account. The caller holds s.mu.
+func (s *Server) activate(lease *isbclient.Lease) {
+	s.accounts++
+	lease.Status = isbclient.LeaseStatusActive
+	lease.AwsAccountId = fmt.Sprintf("%012d", 100000000000+s.accounts)
+	lease.StartDate = time.Now().UTC().Format(time.RFC3339)
+	if lease.LeaseDurationInHours > 0 {
+		lease.ExpirationDate = time.Now().UTC().Add(time.Duration(lease.LeaseDurationInHours) * time.Hour).Format(time.RFC3339)
+	}
+}
+
+// lease returns the lease with the given ID, or nil. The caller holds s.mu.
+func (s *Server) lease(leaseID string) *isbclient.Lease {
+	for _, lease := range s.leases {
+		if lease.LeaseId == leaseID {
+			return lease
+		}
+	}
+	return nil
+}
+
+func success(w http.ResponseWriter, data any) {
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": data})
+}
+
+func fail(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, map[string]any{
+		"status": "fail",
+		"data":   map[string]any{"errors": []map[string]string{{"message": fmt.Sprintf(format, args...)}}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
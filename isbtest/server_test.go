@@ -0,0 +1,83 @@
+package isbtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	isbclient "github.com/gymshark/aws-go-isb-client"
+)
+
+func TestServer_LeaseLifecycle(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(t)
+	tpl := server.AddTemplate(isbclient.LeaseTemplate{Name: "Reviewed", RequiresApproval: true})
+	client := server.Client("dev@example.com")
+
+	created, err := client.CreateLease(ctx, &isbclient.CreateLeaseRequest{LeaseTemplateUUID: tpl.UUID, Comments: "ci"})
+	if err != nil {
+		t.Fatalf("CreateLease error: %v", err)
+	}
+	lease := created.Lease
+	if lease.Status != isbclient.LeaseStatusPendingApproval || lease.UserEmail != "dev@example.com" || lease.Comments != "ci" {
+		t.Errorf("unexpected lease: %+v", lease)
+	}
+
+	if err := client.ReviewLease(ctx, &isbclient.ReviewLeaseRequest{LeaseID: lease.LeaseId, Action: isbclient.ReviewApprove}); err != nil {
+		t.Fatalf("ReviewLease error: %v", err)
+	}
+	got, err := client.GetLeaseByID(ctx, &isbclient.GetLeaseByIDRequest{LeaseID: lease.LeaseId})
+	if err != nil || got.Lease.Status != isbclient.LeaseStatusActive || got.Lease.AwsAccountId == "" {
+		t.Fatalf("expected an Active lease with an account, got %+v %v", got, err)
+	}
+
+	leases, err := client.FetchAllLeases(ctx, &isbclient.GetLeasesRequest{UserEmail: "dev@example.com"})
+	if err != nil || len(leases.Leases) != 1 {
+		t.Errorf("expected one lease for the user, got %v %v", leases, err)
+	}
+	if other, _ := client.FetchAllLeases(ctx, &isbclient.GetLeasesRequest{UserEmail: "other@example.com"}); len(other.Leases) != 0 {
+		t.Error("expected no leases for another user")
+	}
+
+	if err := client.TerminateLease(ctx, &isbclient.TerminateLeaseRequest{LeaseID: lease.LeaseId}); err != nil {
+		t.Fatalf("TerminateLease error: %v", err)
+	}
+	var conflict *isbclient.LeaseConflictError
+	if err := client.TerminateLease(ctx, &isbclient.TerminateLeaseRequest{LeaseID: lease.LeaseId}); !errors.As(err, &conflict) {
+		t.Errorf("expected a LeaseConflictError terminating twice, got %v", err)
+	}
+	var notFound *isbclient.LeaseNotFoundError
+	if _, err := client.GetLeaseByID(ctx, &isbclient.GetLeaseByIDRequest{LeaseID: "missing"}); !errors.As(err, &notFound) {
+		t.Errorf("expected a LeaseNotFoundError, got %v", err)
+	}
+}
+
+func TestServer_DenyAndMaintenance(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(t)
+	tpl := server.AddTemplate(isbclient.LeaseTemplate{Name: "Reviewed", RequiresApproval: true})
+	client := server.Client(DefaultUser)
+
+	created, err := client.CreateLease(ctx, &isbclient.CreateLeaseRequest{LeaseTemplateUUID: tpl.UUID})
+	if err != nil {
+		t.Fatalf("CreateLease error: %v", err)
+	}
+	if err := server.Review(created.Lease.LeaseId, isbclient.ReviewDeny); err != nil {
+		t.Fatalf("Review error: %v", err)
+	}
+	if lease, _ := server.Lease(created.Lease.LeaseId); lease.Status != isbclient.LeaseStatusTerminated {
+		t.Errorf("expected the lease to be denied, got %s", lease.Status)
+	}
+	if err := server.Review(created.Lease.LeaseId, isbclient.ReviewApprove); err == nil {
+		t.Error("expected an error reviewing a denied lease")
+	}
+
+	server.SetMaintenance(true)
+	cfg, err := client.GetConfigurations(ctx)
+	if err != nil || !cfg.MaintenanceMode {
+		t.Errorf("expected maintenance mode, got %+v %v", cfg, err)
+	}
+	if _, err := client.CreateLease(ctx, &isbclient.CreateLeaseRequest{LeaseTemplateUUID: tpl.UUID}); err == nil {
+		t.Error("expected leases to be refused in maintenance mode")
+	}
+}
@@ -0,0 +1,194 @@
+package isbclient
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxLoggedBody bounds the number of body bytes included in a debug log record.
+const maxLoggedBody = 4 << 10
+
+const redacted = "[REDACTED]"
+
+var (
+	jwtPattern    = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	bearerPattern = regexp.MustCompile(`(?i)bearer\s+[^\s"',]+`)
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// base64Pattern matches tokens that may be encoded lease IDs, which embed the user's email.
+	base64Pattern = regexp.MustCompile(`[A-Za-z0-9+/_-]{16,}={0,2}`)
+)
+
+// LogOptions configures the Logging middleware. Zero fields take the documented defaults.
+type LogOptions struct {
+	Level      slog.Leveler // level of successful calls (default slog.LevelInfo)
+	ErrorLevel slog.Leveler // level of failed calls (default slog.LevelError)
+
+	// LogBodies adds a second, debug-level record per call holding the redacted request
+	// headers, request body and response body, truncated to 4 KiB each. It is off by default.
+	LogBodies bool
+}
+
+// Logging returns middleware that logs every call to logger with its operation, HTTP method,
+// path, status, duration, attempt number and request ID.
+//
+// Bearer tokens, JWTs, email addresses and lease IDs embedding an email address are always
+// redacted from logged values. Register Logging after Retry to log every attempt, or before it
+// to log each call once.
+func Logging(logger *slog.Logger, opts LogOptions) Middleware {
+	if opts.Level == nil {
+		opts.Level = slog.LevelInfo
+	}
+	if opts.ErrorLevel == nil {
+		opts.ErrorLevel = slog.LevelError
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			start := time.Now()
+			res, err := next(ctx, call)
+			duration := time.Since(start)
+
+			level, msg := opts.Level.Level(), "isb call"
+			if err != nil {
+				level, msg = opts.ErrorLevel.Level(), "isb call failed"
+			}
+			if logger.Enabled(ctx, level) {
+				attrs := []slog.Attr{
+					slog.String("operation", call.Operation),
+					slog.Int("attempt", call.Attempt),
+					slog.Duration("duration", duration),
+				}
+				if call.Method != "" {
+					attrs = append(attrs,
+						slog.String("method", call.Method),
+						slog.String("path", redact(call.Path)),
+					)
+				}
+				if call.Response != nil {
+					attrs = append(attrs, slog.Int("status", call.Response.HTTPStatus))
+					if call.Response.RequestID != "" {
+						attrs = append(attrs, slog.String("request_id", call.Response.RequestID))
+					}
+				}
+				if err != nil {
+					attrs = append(attrs, slog.String("error", redact(err.Error())))
+				}
+				logger.LogAttrs(ctx, level, msg, attrs...)
+			}
+
+			if opts.LogBodies && logger.Enabled(ctx, slog.LevelDebug) {
+				logger.LogAttrs(ctx, slog.LevelDebug, "isb call bodies",
+					slog.String("operation", call.Operation),
+					slog.Int("attempt", call.Attempt),
+					slog.Any("headers", redactHeader(call.Header)),
+					slog.String("query", redactQuery(call.Query)),
+					slog.String("request_body", logBody(call.Request)),
+					slog.String("response_body", logBody(res)),
+				)
+			}
+			return res, err
+		}
+	}
+}
+
+// logBody encodes v as JSON for logging, redacted and truncated to maxLoggedBody bytes.
+func logBody(v any) string {
+	if v == nil {
+		return ""
+	}
+	var b []byte
+	switch v := v.(type) {
+	case []byte:
+		b = v
+	case json.RawMessage:
+		b = v
+	default:
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			return "<unencodable " + err.Error() + ">"
+		}
+	}
+	s := redact(string(b))
+	if len(s) > maxLoggedBody {
+		s = s[:maxLoggedBody] + "...(truncated)"
+	}
+	return s
+}
+
+// redactHeader returns a copy of h with Authorization and other credential headers redacted.
+func redactHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for name, values := range h {
+		switch http.CanonicalHeaderKey(name) {
+		case "Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie":
+			out[name] = []string{redacted}
+		default:
+			vs := make([]string, len(values))
+			for i, v := range values {
+				vs[i] = redact(v)
+			}
+			out[name] = vs
+		}
+	}
+	return out
+}
+
+// redactQuery returns q as name=value pairs with each decoded value redacted. The pairs are not
+// percent-encoded, since escaping would hide email addresses from redact.
+func redactQuery(q url.Values) string {
+	names := make([]string, 0, len(q))
+	for name := range q {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs []string
+	for _, name := range names {
+		for _, v := range q[name] {
+			pairs = append(pairs, name+"="+redact(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// redact removes bearer tokens, JWTs, email addresses and base64 tokens embedding an email
+// address (such as lease IDs) from s.
+func redact(s string) string {
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+redacted)
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = base64Pattern.ReplaceAllStringFunc(s, func(tok string) string {
+		// Check path segments first so that "/leases/<id>" keeps its prefix.
+		segments := strings.Split(tok, "/")
+		found := false
+		for i, seg := range segments {
+			if embedsEmail(seg) {
+				segments[i], found = redacted, true
+			}
+		}
+		if found {
+			return strings.Join(segments, "/")
+		}
+		if embedsEmail(tok) {
+			return redacted
+		}
+		return tok
+	})
+	return emailPattern.ReplaceAllString(s, redacted)
+}
+
+// embedsEmail reports whether tok is standard or URL-safe base64, padded or not, of text
+// containing an email address.
+func embedsEmail(tok string) bool {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(tok); err == nil {
+			return emailPattern.Match(b)
+		}
+	}
+	return false
+}
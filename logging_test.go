@@ -0,0 +1,160 @@
+package isbclient
+
+import (
+	"bytes"
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Amzn-RequestId", "req-42")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"uuid":"lease-1","userEmail":"someone@example.com"}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	client.Use(Logging(logger, LogOptions{}))
+
+	if _, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"}); err != nil {
+		t.Fatalf("GetLeaseByID error: %v", err)
+	}
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("expected a single JSON record, got %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"level":      "INFO",
+		"msg":        "isb call",
+		"operation":  "GetLeaseByID",
+		"method":     "GET",
+		"path":       "/leases/lease-1",
+		"status":     float64(200),
+		"attempt":    float64(1),
+		"request_id": "req-42",
+	}
+	for k, v := range want {
+		if rec[k] != v {
+			t.Errorf("expected %s=%v, got %v", k, v, rec[k])
+		}
+	}
+	if _, ok := rec["duration"]; !ok {
+		t.Error("expected a duration attribute")
+	}
+	if strings.Contains(buf.String(), "someone@example.com") {
+		t.Error("response body must not be logged without LogBodies")
+	}
+}
+
+func TestLogging_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"status":"fail","data":{"errors":[{"message":"no lease for someone@example.com"}]}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	client.Use(Logging(logger, LogOptions{ErrorLevel: slog.LevelWarn}))
+
+	if _, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "missing"}); err == nil {
+		t.Fatal("expected an error")
+	}
+	out := buf.String()
+	if !strings.Contains(out, `"level":"WARN"`) || !strings.Contains(out, `"msg":"isb call failed"`) || !strings.Contains(out, `"status":404`) {
+		t.Errorf("expected a warning record for the failed call, got %s", out)
+	}
+	if strings.Contains(out, "someone@example.com") {
+		t.Errorf("expected email in error to be redacted, got %s", out)
+	}
+}
+
+func TestLogging_Bodies(t *testing.T) {
+	token, err := GenerateJWT(NewAdminUserClaims("admin@example.com"), "secret", time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateJWT error: %v", err)
+	}
+	leaseID := b64.StdEncoding.EncodeToString([]byte(`{"userEmail":"owner@example.com","uuid":"lease-1"}`))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"uuid":"lease-1","leaseId":"` + leaseID + `","userEmail":"owner@example.com"}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, token)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client.Use(func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			call.Header.Set("Authorization", "Bearer "+token)
+			call.Header.Set("X-Note", "for owner@example.com")
+			return next(ctx, call)
+		}
+	}, Logging(logger, LogOptions{LogBodies: true}))
+
+	_, err = client.CreateLease(context.Background(), &CreateLeaseRequest{LeaseTemplateUUID: "tpl", Comments: "ping owner@example.com"})
+	if err != nil {
+		t.Fatalf("CreateLease error: %v", err)
+	}
+	out := buf.String()
+	for _, secret := range []string{token, "owner@example.com", "admin@example.com", leaseID} {
+		if strings.Contains(out, secret) {
+			t.Errorf("expected %q to be redacted, got %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, `"msg":"isb call bodies"`) || !strings.Contains(out, `ping [REDACTED]`) || !strings.Contains(out, `"uuid\":\"lease-1\"`) {
+		t.Errorf("expected a debug record with redacted bodies, got %s", out)
+	}
+}
+
+func TestLogging_BodiesRedactQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"result":[]}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client.Use(Logging(logger, LogOptions{LogBodies: true}))
+
+	if _, err := client.GetLeases(context.Background(), &GetLeasesRequest{UserEmail: "bob@example.com", PageSize: "10"}); err != nil {
+		t.Fatalf("GetLeases error: %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "bob") {
+		t.Errorf("expected the user email to be redacted, got %s", out)
+	}
+	if !strings.Contains(out, `"query":"pageSize=10&userEmail=[REDACTED]"`) {
+		t.Errorf("expected the redacted query, got %s", out)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Bearer abc.def.ghi", "Bearer [REDACTED]"},
+		{"token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiJ4In0.sig", "token [REDACTED]"},
+		{`{"userEmail":"a.b@example.co.uk"}`, `{"userEmail":"[REDACTED]"}`},
+		{"/leases/" + b64.StdEncoding.EncodeToString([]byte(`{"userEmail":"a@b.io","uuid":"x"}`)), "/leases/[REDACTED]"},
+		{"/leaseTemplates/12345678-90ab-cdef-1234-567890abcdef", "/leaseTemplates/12345678-90ab-cdef-1234-567890abcdef"},
+	}
+	for _, tt := range tests {
+		if got := redact(tt.in); got != tt.want {
+			t.Errorf("redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
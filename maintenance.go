@@ -0,0 +1,56 @@
+package isbclient
+
+import (
+	"context"
+	"time"
+)
+
+// leaseCreatingOperations are the operations refused while the ISB is in maintenance mode.
+var leaseCreatingOperations = map[string]bool{
+	"CreateLease":       true,
+	"CreateLeaseAsUser": true,
+}
+
+// MaintenancePreflight returns middleware that checks the global configuration of c before
+// creating a lease and fails with a *MaintenanceModeError, without calling the API, if
+// maintenance mode is enabled. Register a Cache before it so that the configuration is not
+// fetched for every lease. If the configuration cannot be fetched the lease request is sent
+// anyway and the API decides.
+func MaintenancePreflight(c *Client) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			if leaseCreatingOperations[call.Operation] {
+				if cfg, err := c.GetConfigurations(ctx); err == nil && cfg.MaintenanceMode {
+					return nil, &MaintenanceModeError{}
+				}
+			}
+			return next(ctx, call)
+		}
+	}
+}
+
+// WaitForMaintenanceEnd blocks until the ISB is out of maintenance mode, checking the global
+// configuration every pollInterval (default 30s). It returns immediately if maintenance mode is
+// off, and returns the context's error if ctx is done first or the error of a failed check.
+// Checks bypass any Cache installed on the client.
+func (c *Client) WaitForMaintenanceEnd(ctx context.Context, pollInterval time.Duration) error {
+	if pollInterval <= 0 {
+		pollInterval = 30 * time.Second
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		cfg, err := c.GetConfigurations(withoutCachedValue(ctx))
+		if err != nil {
+			return err
+		}
+		if !cfg.MaintenanceMode {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
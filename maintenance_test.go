@@ -0,0 +1,141 @@
+package isbclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceModeError_FromAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"status":"fail","data":{"errors":[{"message":"Cannot create lease while Innovation Sandbox is in Maintenance Mode."}]}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	_, err := client.CreateLease(context.Background(), &CreateLeaseRequest{LeaseTemplateUUID: "tpl"})
+	var maintenance *MaintenanceModeError
+	if !errors.As(err, &maintenance) {
+		t.Fatalf("expected MaintenanceModeError, got %T %v", err, err)
+	}
+	var badRequest *BadRequestError
+	if !errors.As(err, &badRequest) {
+		t.Errorf("expected the underlying BadRequestError to remain available, got %T", maintenance.Err)
+	}
+	if ErrorCategory(err) != "maintenance_mode" {
+		t.Errorf("expected category maintenance_mode, got %q", ErrorCategory(err))
+	}
+}
+
+func TestMaintenanceModeError_OnlyForLeaseRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/leases":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"status":"fail","data":{"errors":[{"message":"Lease template not found"}]}}`))
+		case "/leaseTemplates/tpl":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"status":"fail","data":{"errors":[{"message":"Description mentions maintenance mode"}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"status":"fail","data":{"errors":[{"message":"Lease of maintenance-team not found"}]}}`))
+		}
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	ctx := context.Background()
+
+	_, err := client.CreateLease(ctx, &CreateLeaseRequest{LeaseTemplateUUID: "tpl"})
+	_, err2 := client.UpdateLeaseTemplate(ctx, &UpdateLeaseTemplateRequest{LeaseTemplateID: "tpl", Name: "maintenance mode"})
+	_, err3 := client.GetLeaseByID(ctx, &GetLeaseByIDRequest{LeaseID: "maintenance"})
+	for _, err := range []error{err, err2, err3} {
+		var maintenance *MaintenanceModeError
+		if err == nil || errors.As(err, &maintenance) {
+			t.Errorf("expected an error other than MaintenanceModeError, got %T %v", err, err)
+		}
+	}
+}
+
+func TestMaintenancePreflight(t *testing.T) {
+	var maintenance atomic.Bool
+	var configHits, leaseHits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/configurations" {
+			configHits.Add(1)
+			fmt.Fprintf(w, `{"status":"success","data":{"maintenanceMode":%t}}`, maintenance.Load())
+			return
+		}
+		leaseHits.Add(1)
+		_, _ = w.Write([]byte(`{"status":"success","data":{"uuid":"lease-1"}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	cache := NewCache(CacheOptions{})
+	client.Use(cache.Middleware(), MaintenancePreflight(client))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.CreateLease(ctx, &CreateLeaseRequest{LeaseTemplateUUID: "tpl"}); err != nil {
+			t.Fatalf("CreateLease error: %v", err)
+		}
+	}
+	if configHits.Load() != 1 || leaseHits.Load() != 2 {
+		t.Errorf("expected 1 cached configuration read and 2 leases, got %d and %d", configHits.Load(), leaseHits.Load())
+	}
+
+	maintenance.Store(true)
+	cache.Invalidate(CacheConfiguration)
+	_, err := client.CreateLease(ctx, &CreateLeaseRequest{LeaseTemplateUUID: "tpl"})
+	var maintenanceErr *MaintenanceModeError
+	if !errors.As(err, &maintenanceErr) {
+		t.Fatalf("expected MaintenanceModeError, got %T %v", err, err)
+	}
+	if leaseHits.Load() != 2 {
+		t.Errorf("expected the lease request not to be sent")
+	}
+	if _, err := client.GetLeases(ctx, nil); err != nil {
+		t.Errorf("expected other operations to be unaffected, got %v", err)
+	}
+}
+
+func TestWaitForMaintenanceEnd(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"status":"success","data":{"maintenanceMode":%t}}`, hits.Add(1) < 3)
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	client.Use(NewCache(CacheOptions{}).Middleware())
+
+	if err := client.WaitForMaintenanceEnd(context.Background(), time.Millisecond); err != nil {
+		t.Fatalf("WaitForMaintenanceEnd error: %v", err)
+	}
+	if hits.Load() != 3 {
+		t.Errorf("expected 3 checks bypassing the cache, got %d", hits.Load())
+	}
+}
+
+func TestWaitForMaintenanceEnd_ContextDone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"maintenanceMode":true}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.WaitForMaintenanceEnd(ctx, time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package isbclient
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Metrics receives measurements of client calls from the RecordMetrics middleware.
+// Implementations must be safe for concurrent use. The promisb module provides a Prometheus
+// implementation.
+type Metrics interface {
+	// ObserveCall records a completed call.
+	ObserveCall(CallObservation)
+	// ObservePage records a page fetched by a paginating operation such as FetchAllLeases.
+	ObservePage(operation string)
+}
+
+// CallObservation describes a completed call.
+type CallObservation struct {
+	Operation     string        // client method name, e.g. "CreateLease"
+	Method        string        // HTTP method, empty for operations made of several requests
+	StatusClass   string        // "2xx", "4xx", "5xx" etc., "error" if no response was received, "none" for operations made of several requests
+	Duration      time.Duration // time taken by the call including retries
+	Retries       int           // number of retries made by a Retry middleware registered after RecordMetrics
+	ErrorCategory string        // ErrorCategory of the returned error, empty on success
+}
+
+type metricsParentKey struct{}
+
+// RecordMetrics returns middleware that reports every call to m. Register it before Retry so
+// that each call is observed once with its retry count.
+func RecordMetrics(m Metrics) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			if parent, ok := ctx.Value(metricsParentKey{}).(string); ok && call.Page > 0 {
+				m.ObservePage(parent)
+			}
+			start := time.Now()
+			res, err := next(context.WithValue(ctx, metricsParentKey{}, call.Operation), call)
+			m.ObserveCall(CallObservation{
+				Operation:     call.Operation,
+				Method:        call.Method,
+				StatusClass:   statusClass(call, err),
+				Duration:      time.Since(start),
+				Retries:       max(call.Attempt-1, 0),
+				ErrorCategory: ErrorCategory(err),
+			})
+			return res, err
+		}
+	}
+}
+
+// statusClass returns the HTTP status class of the call's last response.
+func statusClass(call *Call, err error) string {
+	switch {
+	case call.Response != nil:
+		return strconv.Itoa(call.Response.HTTPStatus/100) + "xx"
+	case call.Method == "":
+		return "none"
+	case err != nil:
+		return "error"
+	}
+	return "none"
+}
@@ -0,0 +1,131 @@
+package isbclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type recordingMetrics struct {
+	mu    sync.Mutex
+	calls []CallObservation
+	pages map[string]int
+}
+
+func (m *recordingMetrics) ObserveCall(o CallObservation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, o)
+}
+
+func (m *recordingMetrics) ObservePage(operation string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pages == nil {
+		m.pages = map[string]int{}
+	}
+	m.pages[operation]++
+}
+
+func TestRecordMetrics(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if hits.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"status":"error","message":"try again"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"uuid":"lease-1"}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	metrics := &recordingMetrics{}
+	client.Use(RecordMetrics(metrics), Retry(RetryPolicy{BaseDelay: time.Millisecond}))
+
+	if _, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"}); err != nil {
+		t.Fatalf("GetLeaseByID error: %v", err)
+	}
+	if len(metrics.calls) != 1 {
+		t.Fatalf("expected 1 observation, got %d", len(metrics.calls))
+	}
+	o := metrics.calls[0]
+	if o.Operation != "GetLeaseByID" || o.Method != http.MethodGet || o.StatusClass != "2xx" || o.Retries != 1 || o.ErrorCategory != "" || o.Duration <= 0 {
+		t.Errorf("unexpected observation: %+v", o)
+	}
+}
+
+func TestRecordMetrics_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"status":"fail","data":{"errors":[{"message":"not found"}]}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	metrics := &recordingMetrics{}
+	client.Use(RecordMetrics(metrics))
+
+	if _, err := client.GetLeaseTemplateByID(context.Background(), &GetLeaseTemplateByIDRequest{LeaseTemplateID: "tpl"}); err == nil {
+		t.Fatal("expected an error")
+	}
+	o := metrics.calls[0]
+	if o.StatusClass != "4xx" || o.ErrorCategory != "lease_template_not_found" || o.Retries != 0 {
+		t.Errorf("unexpected observation: %+v", o)
+	}
+}
+
+func TestRecordMetrics_Pages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("pageIdentifier") {
+		case "":
+			_, _ = w.Write([]byte(`{"status":"success","data":{"result":[{"uuid":"a"}],"nextPageIdentifier":"2"}}`))
+		case "2":
+			_, _ = w.Write([]byte(`{"status":"success","data":{"result":[{"uuid":"b"}],"nextPageIdentifier":"3"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"status":"success","data":{"result":[{"uuid":"c"}],"nextPageIdentifier":""}}`))
+		}
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	metrics := &recordingMetrics{}
+	var pages []int
+	client.Use(RecordMetrics(metrics), func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			if call.Operation == "GetLeases" {
+				pages = append(pages, call.Page)
+			}
+			return next(ctx, call)
+		}
+	})
+
+	if _, err := client.FetchAllLeases(context.Background(), &GetLeasesRequest{}); err != nil {
+		t.Fatalf("FetchAllLeases error: %v", err)
+	}
+	if metrics.pages["FetchAllLeases"] != 3 {
+		t.Errorf("expected 3 pages, got %v", metrics.pages)
+	}
+	if len(metrics.calls) != 4 {
+		t.Fatalf("expected 4 observations, got %d", len(metrics.calls))
+	}
+	if last := metrics.calls[3]; last.Operation != "FetchAllLeases" || last.StatusClass != "none" {
+		t.Errorf("unexpected observation of the paginating call: %+v", last)
+	}
+	if !reflect.DeepEqual(pages, []int{1, 2, 3}) {
+		t.Errorf("expected GetLeases calls for pages 1 to 3, got %v", pages)
+	}
+
+	// A single page requested directly is not counted.
+	if _, err := client.GetLeases(context.Background(), &GetLeasesRequest{}); err != nil {
+		t.Fatalf("GetLeases error: %v", err)
+	}
+	if metrics.pages["FetchAllLeases"] != 3 || len(metrics.pages) != 1 || pages[3] != 0 {
+		t.Errorf("expected a direct GetLeases call not to be a page, got %v and %v", metrics.pages, pages)
+	}
+}
@@ -0,0 +1,179 @@
+package isbclient
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Call describes a logical API call as seen by middleware.
+//
+// Every client method is one logical call, named after the method (for example "CreateLease"
+// or "FetchAllLeases"). Methods built from other methods, such as FetchAllLeases or
+// PatchLeaseTemplate, are calls of their own and have no Method or Path; the requests they make
+// pass through the middleware chain again as nested calls.
+type Call struct {
+	Operation string      // client method name, e.g. "CreateLease"
+	Request   any         // request value passed to the method, may be nil
+	Method    string      // HTTP method, empty for operations made of several requests
+	Path      string      // request path relative to Client.BaseURL, empty for operations made of several requests
+	Query     url.Values  // query parameters, may be nil
+	Attempt   int         // 1-based attempt number, advanced by Retry
+	Header    http.Header // extra headers set on the outbound request
+	Page      int         // 1-based page number when fetched by a paginating operation such as FetchAllLeases, else 0
+
+	// Response describes the last HTTP response received for this call. It is nil until the
+	// call has been sent and for operations made of several requests.
+	Response *ResponseMetadata
+
+	// captureBody asks send to keep the raw body of a successful response in responseBody,
+	// for ValidateResponses.
+	captureBody  bool
+	responseBody []byte
+
+	// token, when set by Authenticate, replaces Client.Token for this call.
+	token string
+
+	// checkToken, when set by RolePreflight, is called by send with the token the request is
+	// about to be sent with; an error fails the call without sending it.
+	checkToken func(token string) error
+}
+
+// Handler performs a logical API call and returns the value the client method returns
+// (for example *CreateLeaseResponse), or nil when it fails.
+type Handler func(ctx context.Context, call *Call) (any, error)
+
+// Middleware wraps a Handler. It may inspect or modify the call, short-circuit it by
+// returning without calling next, or act on the result.
+type Middleware func(next Handler) Handler
+
+type callKey struct{}
+
+// pageKey marks the context of a call fetching a page for paginateAll with the page number.
+type pageKey struct{}
+
+// Use appends middleware to the client's chain. The first middleware registered is the
+// outermost: it sees the call first and the result last. Use must not be called while the
+// client is in use.
+func (c *Client) Use(mw ...Middleware) {
+	c.middleware = append(c.middleware, mw...)
+}
+
+// invoke runs fn as the logical call op through the client's middleware chain.
+func invoke[T any](ctx context.Context, c *Client, op string, req any, ac apiCall, fn func(context.Context) (T, error)) (T, error) {
+	if len(c.middleware) == 0 {
+		return fn(ctx)
+	}
+	call := &Call{
+		Operation: op,
+		Request:   req,
+		Method:    ac.method,
+		Path:      ac.path,
+		Query:     ac.query,
+		Attempt:   1,
+		Header:    http.Header{},
+	}
+	call.Page, _ = ctx.Value(pageKey{}).(int)
+	h := Handler(func(ctx context.Context, call *Call) (any, error) {
+		ctx = context.WithValue(ctx, pageKey{}, nil)
+		res, err := fn(context.WithValue(ctx, callKey{}, call))
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	})
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		h = c.middleware[i](h)
+	}
+	res, err := h(ctx, call)
+	out, _ := res.(T)
+	return out, err
+}
+
+// callFromContext returns the Call being performed with ctx, if any.
+func callFromContext(ctx context.Context) *Call {
+	call, _ := ctx.Value(callKey{}).(*Call)
+	return call
+}
+
+// RetryPolicy configures the Retry middleware. Zero fields take the documented defaults.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first (default 3)
+	BaseDelay   time.Duration // delay before the first retry, doubled for each further one (default 200ms)
+	MaxDelay    time.Duration // upper bound for a single delay (default 5s)
+
+	// Retryable decides whether a failed call is tried again. Defaults to IsRetryable.
+	Retryable func(call *Call, err error) bool
+}
+
+// Retry returns middleware that retries failed calls according to policy, with jittered
+// exponential backoff between attempts.
+func Retry(policy RetryPolicy) Middleware {
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = 3
+	}
+	if policy.BaseDelay == 0 {
+		policy.BaseDelay = 200 * time.Millisecond
+	}
+	if policy.MaxDelay == 0 {
+		policy.MaxDelay = 5 * time.Second
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsRetryable
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			for attempt := 1; ; attempt++ {
+				call.Attempt = attempt
+				call.Response = nil
+				res, err := next(ctx, call)
+				if err == nil || ctx.Err() != nil || attempt >= policy.MaxAttempts || !policy.Retryable(call, err) {
+					return res, err
+				}
+				delay := min(policy.BaseDelay<<(attempt-1), policy.MaxDelay)
+				delay = delay/2 + rand.N(delay/2+1)
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return res, err
+				case <-timer.C:
+				}
+			}
+		}
+	}
+}
+
+// IsRetryable reports whether a failed call is safe and worthwhile to repeat: it must be a
+// single idempotent request (GET, PUT or DELETE) that failed in transport or was answered
+// with a 5xx status or 429 Too Many Requests.
+func IsRetryable(call *Call, err error) bool {
+	switch call.Method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if call.Response != nil {
+		status := call.Response.HTTPStatus
+		return status >= 500 || status == http.StatusTooManyRequests
+	}
+	var reqErr *APIRequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.Op == "do"
+	}
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		return true
+	}
+	var respErr *APIResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode >= 500 || respErr.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+package isbclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUse_ChainOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"uuid":"lease-1"}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) (any, error) {
+				order = append(order, name+" before")
+				res, err := next(ctx, call)
+				order = append(order, name+" after")
+				return res, err
+			}
+		}
+	}
+	client.Use(trace("outer"), trace("inner"))
+
+	if _, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"}); err != nil {
+		t.Fatalf("GetLeaseByID error: %v", err)
+	}
+	want := []string{"outer before", "inner before", "inner after", "outer after"}
+	if len(order) != len(want) {
+		t.Fatalf("expected %v, got %v", want, order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, order)
+		}
+	}
+}
+
+func TestUse_SeesCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Trace"); got != "abc" {
+			t.Errorf("expected header set by middleware, got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req-1")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"uuid":"lease-1"}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	req := &GetLeaseByIDRequest{LeaseID: "lease-1"}
+	var seen *Call
+	var result any
+	client.Use(func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			call.Header.Set("X-Trace", "abc")
+			res, err := next(ctx, call)
+			seen, result = call, res
+			return res, err
+		}
+	})
+
+	resp, err := client.GetLeaseByID(context.Background(), req)
+	if err != nil {
+		t.Fatalf("GetLeaseByID error: %v", err)
+	}
+	if seen.Operation != "GetLeaseByID" || seen.Method != http.MethodGet || seen.Path != "/leases/lease-1" {
+		t.Errorf("unexpected call: %+v", seen)
+	}
+	if seen.Request != req {
+		t.Errorf("expected the request value, got %v", seen.Request)
+	}
+	if result != resp {
+		t.Errorf("expected middleware to see the method result, got %v", result)
+	}
+	if seen.Response == nil || seen.Response.HTTPStatus != http.StatusOK || seen.Response.RequestID != "req-1" {
+		t.Errorf("expected response metadata, got %+v", seen.Response)
+	}
+}
+
+func TestUse_ShortCircuit(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	cached := &GetLeaseByIDResponse{Lease: Lease{UUID: "cached"}}
+	denied := errors.New("denied")
+	client.Use(func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			switch call.Operation {
+			case "GetLeaseByID":
+				return cached, nil
+			case "TerminateLease":
+				return nil, denied
+			}
+			return next(ctx, call)
+		}
+	})
+
+	resp, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"})
+	if err != nil || resp != cached {
+		t.Errorf("expected the short-circuited result, got %v, %v", resp, err)
+	}
+	if err := client.TerminateLease(context.Background(), &TerminateLeaseRequest{LeaseID: "lease-1"}); err != denied {
+		t.Errorf("expected the short-circuited error, got %v", err)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("expected no requests to reach the server, got %d", hits.Load())
+	}
+}
+
+func TestUse_NestedCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("pageIdentifier") == "" {
+			_, _ = w.Write([]byte(`{"status":"success","data":{"result":[{"uuid":"a"}],"nextPageIdentifier":"2"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"result":[{"uuid":"b"}],"nextPageIdentifier":""}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	var ops []string
+	client.Use(func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			ops = append(ops, call.Operation)
+			return next(ctx, call)
+		}
+	})
+	resp, err := client.FetchAllLeases(context.Background(), &GetLeasesRequest{})
+	if err != nil {
+		t.Fatalf("FetchAllLeases error: %v", err)
+	}
+	if len(resp.Leases) != 2 {
+		t.Errorf("expected 2 leases, got %d", len(resp.Leases))
+	}
+	want := []string{"FetchAllLeases", "GetLeases", "GetLeases"}
+	if len(ops) != len(want) || ops[0] != want[0] || ops[1] != want[1] || ops[2] != want[2] {
+		t.Errorf("expected operations %v, got %v", want, ops)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if hits.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"status":"error","message":"try again"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"uuid":"lease-1"}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	var attempts []int
+	client.Use(Retry(RetryPolicy{BaseDelay: time.Millisecond}), func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			attempts = append(attempts, call.Attempt)
+			return next(ctx, call)
+		}
+	})
+
+	resp, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"})
+	if err != nil {
+		t.Fatalf("GetLeaseByID error: %v", err)
+	}
+	if resp.Lease.UUID != "lease-1" {
+		t.Errorf("unexpected lease: %+v", resp.Lease)
+	}
+	if len(attempts) != 3 || attempts[0] != 1 || attempts[2] != 3 {
+		t.Errorf("expected attempts [1 2 3], got %v", attempts)
+	}
+}
+
+func TestRetry_GivesUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		call     func(*Client) error
+		wantHits int32
+	}{
+		{"exhausted", http.StatusInternalServerError, func(c *Client) error {
+			_, err := c.GetLeases(context.Background(), nil)
+			return err
+		}, 2},
+		{"not idempotent", http.StatusInternalServerError, func(c *Client) error {
+			_, err := c.CreateLease(context.Background(), &CreateLeaseRequest{LeaseTemplateUUID: "tpl"})
+			return err
+		}, 1},
+		{"client error", http.StatusBadRequest, func(c *Client) error {
+			_, err := c.GetLeases(context.Background(), nil)
+			return err
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits.Add(1)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(`{"status":"error","message":"boom"}`))
+			}))
+			defer server.Close()
+			client := NewClient(server.URL, "token")
+			client.Use(Retry(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}))
+
+			if err := tt.call(client); err == nil {
+				t.Fatal("expected an error")
+			}
+			if hits.Load() != tt.wantHits {
+				t.Errorf("expected %d requests, got %d", tt.wantHits, hits.Load())
+			}
+		})
+	}
+}
+
+func TestRetry_StopsOnContextDone(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"status":"error","message":"boom"}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	client.Use(Retry(RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.GetLeases(ctx, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("expected Retry to stop waiting when the context is done")
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected 1 request, got %d", hits.Load())
+	}
+}
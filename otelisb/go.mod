@@ -0,0 +1,27 @@
+module github.com/gymshark/aws-go-isb-client/otelisb
+
+go 1.24.5
+
+require (
+	github.com/gymshark/aws-go-isb-client v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel v1.41.0
+	go.opentelemetry.io/otel/sdk v1.41.0
+	go.opentelemetry.io/otel/trace v1.41.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/golang-jwt/jwt/v5 v5.3.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.2.1 // indirect
+	go.opentelemetry.io/otel/metric v1.41.0 // indirect
+	golang.org/x/sys v0.41.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The root module has no tagged release yet, so this module is built against the copy in
+// this repository. Release order: tag the root module, require that tag above in place of
+// the zero version, remove this replace, then tag otelisb/vX.Y.Z.
+replace github.com/gymshark/aws-go-isb-client => ../
@@ -0,0 +1,207 @@
+// Package otelisb provides OpenTelemetry tracing for the ISB client.
+//
+// It lives in its own module so that users of the client who do not trace do not depend on
+// OpenTelemetry.
+//
+//	client.Use(otelisb.Middleware())
+package otelisb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	isbclient "github.com/gymshark/aws-go-isb-client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name of the tracer used by Middleware.
+const ScopeName = "github.com/gymshark/aws-go-isb-client/otelisb"
+
+// Span attribute keys set by Middleware.
+const (
+	OperationKey         = attribute.Key("isb.operation")
+	AttemptKey           = attribute.Key("isb.attempt")
+	RequestIDKey         = attribute.Key("isb.request_id")
+	LeaseUUIDKey         = attribute.Key("isb.lease.uuid")
+	LeaseTemplateUUIDKey = attribute.Key("isb.lease_template.uuid")
+	AccountIDKey         = attribute.Key("isb.account.id")
+	HTTPMethodKey        = attribute.Key("http.request.method")
+	HTTPStatusKey        = attribute.Key("http.response.status_code")
+	URLPathKey           = attribute.Key("url.path")
+	ErrorTypeKey         = attribute.Key("error.type")
+)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// Option configures Middleware.
+type Option func(*config)
+
+// WithTracerProvider sets the TracerProvider spans are created with. Defaults to the global
+// provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) { c.tracerProvider = tp }
+}
+
+// WithPropagator sets the propagator used to inject trace context into outbound requests.
+// Defaults to the global propagator.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *config) { c.propagator = p }
+}
+
+// Middleware returns client middleware that creates a span named "isb.<Operation>" for every
+// call and injects the trace context into the outbound request headers.
+//
+// Operations made of several requests, such as FetchAllLeases, get an internal span with a
+// client span per request beneath it. Spans carry the lease UUID, lease template UUID and
+// account ID involved where known, and failed calls record isbclient.ErrorCategory as
+// error.type. Lease IDs are not recorded as they embed the user's email address; neither are
+// error messages, which may contain request bodies.
+func Middleware(opts ...Option) isbclient.Middleware {
+	cfg := config{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.tracerProvider == nil {
+		cfg.tracerProvider = otel.GetTracerProvider()
+	}
+	if cfg.propagator == nil {
+		cfg.propagator = otel.GetTextMapPropagator()
+	}
+	tracer := cfg.tracerProvider.Tracer(ScopeName)
+
+	return func(next isbclient.Handler) isbclient.Handler {
+		return func(ctx context.Context, call *isbclient.Call) (any, error) {
+			kind := trace.SpanKindInternal
+			attrs := []attribute.KeyValue{OperationKey.String(call.Operation)}
+			if call.Method != "" {
+				kind = trace.SpanKindClient
+				attrs = append(attrs,
+					HTTPMethodKey.String(call.Method),
+					URLPathKey.String(redactPath(call.Path)),
+					AttemptKey.Int(call.Attempt),
+				)
+			}
+			attrs = append(attrs, resourceAttributes(call.Request)...)
+
+			ctx, span := tracer.Start(ctx, "isb."+call.Operation, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+			defer span.End()
+			if call.Method != "" {
+				cfg.propagator.Inject(ctx, propagation.HeaderCarrier(call.Header))
+			}
+
+			res, err := next(ctx, call)
+
+			if call.Response != nil {
+				span.SetAttributes(HTTPStatusKey.Int(call.Response.HTTPStatus))
+				if call.Response.RequestID != "" {
+					span.SetAttributes(RequestIDKey.String(call.Response.RequestID))
+				}
+			}
+			if err != nil {
+				category := isbclient.ErrorCategory(err)
+				span.SetAttributes(ErrorTypeKey.String(category))
+				span.SetStatus(codes.Error, category)
+			} else {
+				span.SetAttributes(resourceAttributes(res)...)
+			}
+			return res, err
+		}
+	}
+}
+
+// resourceAttributes returns the lease, lease template and account attributes of a request or
+// response value.
+func resourceAttributes(v any) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	add := func(key attribute.Key, value string) {
+		if value != "" {
+			attrs = append(attrs, key.String(value))
+		}
+	}
+	addLease := func(l isbclient.Lease) {
+		add(LeaseUUIDKey, l.UUID)
+		add(LeaseTemplateUUIDKey, l.OriginalLeaseTemplateUuid)
+		add(AccountIDKey, l.AwsAccountId)
+	}
+
+	switch v := v.(type) {
+	case *isbclient.GetLeaseByIDRequest:
+		add(LeaseUUIDKey, leaseUUID(v.LeaseID))
+	case *isbclient.UpdateLeaseRequest:
+		add(LeaseUUIDKey, leaseUUID(v.LeaseID))
+	case *isbclient.ReviewLeaseRequest:
+		add(LeaseUUIDKey, leaseUUID(v.LeaseID))
+	case *isbclient.FreezeLeaseRequest:
+		add(LeaseUUIDKey, leaseUUID(v.LeaseID))
+	case *isbclient.TerminateLeaseRequest:
+		add(LeaseUUIDKey, leaseUUID(v.LeaseID))
+	case *isbclient.CreateLeaseRequest:
+		add(LeaseTemplateUUIDKey, v.LeaseTemplateUUID)
+	case *isbclient.UpdateLeaseTemplateRequest:
+		add(LeaseTemplateUUIDKey, v.LeaseTemplateID)
+	case *isbclient.GetLeaseTemplateByIDRequest:
+		add(LeaseTemplateUUIDKey, v.LeaseTemplateID)
+	case *isbclient.DeleteLeaseTemplateRequest:
+		add(LeaseTemplateUUIDKey, v.LeaseTemplateID)
+	case *isbclient.RegisterAccountRequest:
+		add(AccountIDKey, v.AwsAccountId)
+	case *isbclient.RetryCleanupRequest:
+		add(AccountIDKey, v.AwsAccountId)
+	case *isbclient.EjectAccountRequest:
+		add(AccountIDKey, v.AwsAccountId)
+
+	case *isbclient.GetLeaseByIDResponse:
+		addLease(v.Lease)
+	case *isbclient.CreateLeaseResponse:
+		addLease(v.Lease)
+	case *isbclient.UpdateLeaseResponse:
+		addLease(v.Lease)
+	case *isbclient.UpdateLeaseTemplateResponse:
+		add(LeaseTemplateUUIDKey, v.LeaseTemplate.UUID)
+	case *isbclient.GetLeaseTemplateByIDResponse:
+		add(LeaseTemplateUUIDKey, v.LeaseTemplate.UUID)
+	case *isbclient.RegisterAccountResponse:
+		add(AccountIDKey, v.Account.AwsAccountId)
+	}
+	return attrs
+}
+
+// leaseUUID returns the UUID encoded in a lease ID, or id itself if it is not an encoded lease ID.
+func leaseUUID(id string) string {
+	b, err := base64.StdEncoding.DecodeString(id)
+	if err != nil {
+		return id
+	}
+	var decoded struct {
+		UUID string `json:"uuid"`
+	}
+	if json.Unmarshal(b, &decoded) != nil || decoded.UUID == "" {
+		return ""
+	}
+	return decoded.UUID
+}
+
+// redactPath replaces encoded lease IDs in path with their UUID.
+func redactPath(path string) string {
+	rest, ok := strings.CutPrefix(path, "/leases/")
+	if !ok {
+		return path
+	}
+	id, tail, found := strings.Cut(rest, "/")
+	if found {
+		tail = "/" + tail
+	}
+	if uuid := leaseUUID(id); uuid != "" {
+		return "/leases/" + uuid + tail
+	}
+	return "/leases/{leaseId}" + tail
+}
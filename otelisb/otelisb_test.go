@@ -0,0 +1,145 @@
+package otelisb
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	isbclient "github.com/gymshark/aws-go-isb-client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTracedClient(t *testing.T, handler http.HandlerFunc) (*isbclient.Client, *tracetest.InMemoryExporter) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	client := isbclient.NewClient(server.URL, "token")
+	client.Use(Middleware(WithTracerProvider(tp), WithPropagator(propagation.TraceContext{})))
+	return client, exporter
+}
+
+func attrs(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	m := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestMiddleware_CreateLease(t *testing.T) {
+	var traceparent string
+	client, exporter := newTracedClient(t, func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Amzn-RequestId", "req-1")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"uuid":"lease-uuid","userEmail":"a@example.com","originalLeaseTemplateUuid":"tpl-uuid","awsAccountId":"123456789012"}}`))
+	})
+
+	if _, err := client.CreateLease(context.Background(), &isbclient.CreateLeaseRequest{LeaseTemplateUUID: "tpl-uuid"}); err != nil {
+		t.Fatalf("CreateLease error: %v", err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "isb.CreateLease" || span.SpanKind != trace.SpanKindClient {
+		t.Errorf("unexpected span %q of kind %v", span.Name, span.SpanKind)
+	}
+	got := attrs(span)
+	want := map[attribute.Key]string{
+		OperationKey:         "CreateLease",
+		HTTPMethodKey:        "POST",
+		URLPathKey:           "/leases",
+		RequestIDKey:         "req-1",
+		LeaseUUIDKey:         "lease-uuid",
+		LeaseTemplateUUIDKey: "tpl-uuid",
+		AccountIDKey:         "123456789012",
+	}
+	for k, v := range want {
+		if got[k].AsString() != v {
+			t.Errorf("expected %s=%q, got %q", k, v, got[k].AsString())
+		}
+	}
+	if got[HTTPStatusKey].AsInt64() != 200 {
+		t.Errorf("expected status 200, got %v", got[HTTPStatusKey])
+	}
+	wantParent := "00-" + span.SpanContext.TraceID().String() + "-" + span.SpanContext.SpanID().String() + "-01"
+	if traceparent != wantParent {
+		t.Errorf("expected traceparent %q, got %q", wantParent, traceparent)
+	}
+}
+
+func TestMiddleware_FetchAllChildSpans(t *testing.T) {
+	client, exporter := newTracedClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("pageIdentifier") == "" {
+			_, _ = w.Write([]byte(`{"status":"success","data":{"result":[{"uuid":"a"}],"nextPageIdentifier":"2"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"result":[{"uuid":"b"}],"nextPageIdentifier":""}}`))
+	})
+
+	if _, err := client.FetchAllLeases(context.Background(), &isbclient.GetLeasesRequest{}); err != nil {
+		t.Fatalf("FetchAllLeases error: %v", err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	// Spans are exported as they end: both pages, then the parent.
+	parent := spans[2]
+	if parent.Name != "isb.FetchAllLeases" || parent.SpanKind != trace.SpanKindInternal {
+		t.Fatalf("unexpected parent span %q of kind %v", parent.Name, parent.SpanKind)
+	}
+	for _, child := range spans[:2] {
+		if child.Name != "isb.GetLeases" {
+			t.Errorf("expected page span isb.GetLeases, got %q", child.Name)
+		}
+		if child.Parent.SpanID() != parent.SpanContext.SpanID() {
+			t.Errorf("expected page span to be a child of %s", parent.Name)
+		}
+	}
+}
+
+func TestMiddleware_Error(t *testing.T) {
+	leaseID := b64.StdEncoding.EncodeToString([]byte(`{"userEmail":"a@example.com","uuid":"lease-uuid"}`))
+	client, exporter := newTracedClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"status":"fail","data":{"errors":[{"message":"not found"}]}}`))
+	})
+
+	if err := client.TerminateLease(context.Background(), &isbclient.TerminateLeaseRequest{LeaseID: leaseID}); err == nil {
+		t.Fatal("expected an error")
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Status.Code != codes.Error || span.Status.Description != "lease_not_found" {
+		t.Errorf("expected error status lease_not_found, got %+v", span.Status)
+	}
+	got := attrs(span)
+	if got[ErrorTypeKey].AsString() != "lease_not_found" {
+		t.Errorf("expected error.type lease_not_found, got %q", got[ErrorTypeKey].AsString())
+	}
+	if got[LeaseUUIDKey].AsString() != "lease-uuid" {
+		t.Errorf("expected lease UUID decoded from the lease ID, got %q", got[LeaseUUIDKey].AsString())
+	}
+	if got[URLPathKey].AsString() != "/leases/lease-uuid/terminate" {
+		t.Errorf("expected the lease ID to be replaced in the path, got %q", got[URLPathKey].AsString())
+	}
+}
@@ -0,0 +1,329 @@
+package isbclient
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables read by LoadProfile. Except for ConfigFileEnvVar and ProfileEnvVar,
+// they override the values of the profile.
+const (
+	ConfigFileEnvVar       = "ISB_CONFIG_FILE"
+	ProfileEnvVar          = "ISB_PROFILE"
+	BaseURLEnvVar          = "ISB_BASE_URL"
+	AuthMethodEnvVar       = "ISB_AUTH_METHOD"
+	SecretEnvVar           = "ISB_SECRET"
+	UserEmailEnvVar        = "ISB_USER_EMAIL"
+	UserRolesEnvVar        = "ISB_USER_ROLES" // comma-separated, e.g. "Admin,Manager"
+	PageSizeEnvVar         = "ISB_PAGE_SIZE"
+	TimeoutEnvVar          = "ISB_TIMEOUT"
+	RetryMaxAttemptsEnvVar = "ISB_RETRY_MAX_ATTEMPTS"
+)
+
+// Authentication methods of a profile.
+const (
+	AuthMethodChain  = "chain"  // CredentialChain over all configured sources (default)
+	AuthMethodToken  = "token"  // Auth.Token or ISB_TOKEN only
+	AuthMethodSecret = "secret" // tokens signed with Auth.Secret only
+	AuthMethodNone   = "none"   // no authentication
+)
+
+// ConfigFile is the client configuration file, by default config.yaml in the isb directory
+// under the user's configuration directory. Only YAML is supported:
+//
+//	defaultProfile: dev
+//	profiles:
+//	  dev:
+//	    baseUrl: https://d1234.cloudfront.net/api
+//	    auth:
+//	      method: secret
+//	      secret: env:ISB_DEV_JWT_SECRET
+//	      user:
+//	        email: ci@example.com
+//	        roles: [Admin]
+//	    pageSize: 100
+//	    timeout: 30s
+//	    retry:
+//	      maxAttempts: 5
+type ConfigFile struct {
+	DefaultProfile string             `yaml:"defaultProfile"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+}
+
+// Profile configures a client for one ISB deployment.
+type Profile struct {
+	Name     string        `yaml:"-"`
+	BaseURL  string        `yaml:"baseUrl"`
+	Auth     ProfileAuth   `yaml:"auth"`
+	PageSize int           `yaml:"pageSize"` // Client.DefaultPageSize
+	Timeout  time.Duration `yaml:"timeout"`  // HTTP client timeout, default 15s
+	Retry    ProfileRetry  `yaml:"retry"`
+}
+
+// ProfileAuth configures how a profile's client authenticates.
+type ProfileAuth struct {
+	Method string `yaml:"method"` // one of the AuthMethod constants
+	Token  string `yaml:"token"`
+
+	// Secret references the signing secret; see ParseSecretProvider.
+	Secret         string        `yaml:"secret"`
+	SecretCacheTTL time.Duration `yaml:"secretCacheTTL"`
+
+	// User is the identity signed tokens are issued for. It must name at least one role.
+	User      ProfileUser   `yaml:"user"`
+	ExpiresIn time.Duration `yaml:"expiresIn"`
+}
+
+// ProfileUser is the identity of tokens signed for a profile.
+type ProfileUser struct {
+	Email       string   `yaml:"email"`
+	DisplayName string   `yaml:"displayName"`
+	Roles       []string `yaml:"roles"`
+}
+
+// ProfileRetry configures the Retry middleware of a profile's client. It is installed when
+// MaxAttempts is greater than 1.
+type ProfileRetry struct {
+	MaxAttempts int           `yaml:"maxAttempts"`
+	BaseDelay   time.Duration `yaml:"baseDelay"`
+	MaxDelay    time.Duration `yaml:"maxDelay"`
+}
+
+// ProfileClientOptions supplies what a configuration file cannot: the Secrets Manager call for
+// "secretsmanager:" references and the interactive login of the credential chain.
+type ProfileClientOptions struct {
+	SecretsManager SecretsManagerFetcher
+	Login          LoginFunc
+}
+
+// DefaultConfigPath returns isb/config.yaml under the user's configuration directory, for
+// example ~/.config/isb/config.yaml on Linux.
+func DefaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "isb", "config.yaml"), nil
+}
+
+// LoadConfigFile reads the configuration file at path. A missing file yields an empty
+// configuration.
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	cfg := &ConfigFile{}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// LoadProfile returns the profile name from the configuration file named by ISB_CONFIG_FILE,
+// or at DefaultConfigPath, with environment variables applied on top. An empty name selects
+// ISB_PROFILE, then the file's defaultProfile, then "default"; only a profile named explicitly
+// must exist in the file.
+func LoadProfile(name string) (*Profile, error) {
+	path := os.Getenv(ConfigFileEnvVar)
+	if path == "" {
+		var err error
+		if path, err = DefaultConfigPath(); err != nil {
+			return nil, err
+		}
+	}
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	explicit := name != ""
+	if name == "" {
+		name = os.Getenv(ProfileEnvVar)
+		explicit = name != ""
+	}
+	if name == "" {
+		name = cfg.DefaultProfile
+	}
+	if name == "" {
+		name = "default"
+	}
+	profile, ok := cfg.Profiles[name]
+	if !ok && explicit {
+		return nil, fmt.Errorf("profile %q not found in %s", name, path)
+	}
+	profile.Name = name
+	if err := profile.applyEnv(); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (p *Profile) applyEnv() error {
+	setString := func(dst *string, name string) {
+		if v := os.Getenv(name); v != "" {
+			*dst = v
+		}
+	}
+	setString(&p.BaseURL, BaseURLEnvVar)
+	setString(&p.Auth.Method, AuthMethodEnvVar)
+	setString(&p.Auth.Secret, SecretEnvVar)
+	setString(&p.Auth.User.Email, UserEmailEnvVar)
+	if v := os.Getenv(UserRolesEnvVar); v != "" {
+		p.Auth.User.Roles = nil
+		for _, role := range strings.Split(v, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				p.Auth.User.Roles = append(p.Auth.User.Roles, role)
+			}
+		}
+	}
+	for name, dst := range map[string]*int{PageSizeEnvVar: &p.PageSize, RetryMaxAttemptsEnvVar: &p.Retry.MaxAttempts} {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*dst = n
+		}
+	}
+	if v := os.Getenv(TimeoutEnvVar); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", TimeoutEnvVar, err)
+		}
+		p.Timeout = d
+	}
+	return nil
+}
+
+// NewClient returns a client configured by the profile.
+func (p *Profile) NewClient(opts ProfileClientOptions) (*Client, error) {
+	if p.BaseURL == "" {
+		return nil, fmt.Errorf("profile %q: baseUrl is required (or set %s)", p.Name, BaseURLEnvVar)
+	}
+	c := NewClient(strings.TrimSuffix(p.BaseURL, "/"), "")
+	if p.Timeout > 0 {
+		c.HTTPClient = &http.Client{Timeout: p.Timeout}
+	}
+	c.DefaultPageSize = p.PageSize
+	if p.Retry.MaxAttempts > 1 {
+		c.Use(Retry(RetryPolicy{MaxAttempts: p.Retry.MaxAttempts, BaseDelay: p.Retry.BaseDelay, MaxDelay: p.Retry.MaxDelay}))
+	}
+
+	var secret SecretProvider
+	if p.Auth.Secret != "" {
+		var err error
+		if secret, err = ParseSecretProvider(p.Auth.Secret, p.Auth.SecretCacheTTL, opts.SecretsManager); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+	}
+	var user UserClaims
+	if secret != nil {
+		var err error
+		if user, err = p.Auth.User.claims(); err != nil {
+			return nil, fmt.Errorf("profile %q: user: %w", p.Name, err)
+		}
+	}
+
+	switch p.Auth.Method {
+	case "", AuthMethodChain:
+		c.Use(Authenticate(NewCredentialChain(CredentialChainOptions{
+			Token:     p.Auth.Token,
+			BaseURL:   c.BaseURL,
+			Profile:   p.Name,
+			Secret:    secret,
+			User:      user,
+			ExpiresIn: p.Auth.ExpiresIn,
+			Login:     opts.Login,
+		})))
+	case AuthMethodToken:
+		c.Token = p.Auth.Token
+		if c.Token == "" {
+			c.Token = os.Getenv(TokenEnvVar)
+		}
+		if c.Token == "" {
+			return nil, fmt.Errorf("profile %q: auth method token requires a token or %s", p.Name, TokenEnvVar)
+		}
+	case AuthMethodSecret:
+		if secret == nil {
+			return nil, fmt.Errorf("profile %q: auth method secret requires a secret reference", p.Name)
+		}
+		c.Use(Authenticate(NewSecretTokenSource(secret, SecretTokenOptions{User: user, ExpiresIn: p.Auth.ExpiresIn})))
+	case AuthMethodNone:
+	default:
+		return nil, fmt.Errorf("profile %q: unknown auth method %q", p.Name, p.Auth.Method)
+	}
+	return c, nil
+}
+
+// claims returns the claims of tokens signed for u. Roles are required; they are not defaulted
+// so that a profile never signs tokens with more privileges than it names.
+func (u ProfileUser) claims() (UserClaims, error) {
+	b := NewUserClaimsBuilder(u.Email).Roles(u.Roles...)
+	if u.DisplayName != "" {
+		b.DisplayName(u.DisplayName)
+	}
+	return b.Build()
+}
+
+// ParseSecretProvider returns the SecretProvider a reference names:
+//
+//	env:NAME               EnvSecret(NAME)
+//	file:PATH              FileSecret(PATH)
+//	command:NAME ARGS...   CommandSecret, arguments split on spaces
+//	secretsmanager:ID      SecretsManagerSecret(ID, fetch)
+//
+// Command and Secrets Manager secrets are cached for ttl (default 5 minutes).
+func ParseSecretProvider(ref string, ttl time.Duration, fetch SecretsManagerFetcher) (SecretProvider, error) {
+	kind, value, ok := strings.Cut(ref, ":")
+	if !ok || value == "" {
+		return nil, fmt.Errorf("invalid secret reference %q: want env:, file:, command: or secretsmanager:", ref)
+	}
+	switch kind {
+	case "env":
+		return EnvSecret(value), nil
+	case "file":
+		return FileSecret(value), nil
+	case "command":
+		args := strings.Fields(value)
+		if len(args) == 0 {
+			return nil, fmt.Errorf("invalid secret reference %q: no command", ref)
+		}
+		return NewCachedSecret(CommandSecret(args[0], args[1:]...), ttl), nil
+	case "secretsmanager":
+		if fetch == nil {
+			return nil, fmt.Errorf("secret reference %q requires a Secrets Manager fetcher", ref)
+		}
+		return NewCachedSecret(SecretsManagerSecret(value, fetch), ttl), nil
+	}
+	return nil, fmt.Errorf("invalid secret reference %q: unknown kind %q", ref, kind)
+}
+
+// NewClientFromProfile returns a client configured by the named profile of the configuration
+// file, with environment variables applied on top; see LoadProfile. Profiles referencing
+// Secrets Manager or using interactive login need LoadProfile and Profile.NewClient.
+func NewClientFromProfile(name string) (*Client, error) {
+	profile, err := LoadProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return profile.NewClient(ProfileClientOptions{})
+}
+
+// NewClientFromEnv returns a client configured by the environment: the profile named by
+// ISB_PROFILE, or the default profile, overridden by the other ISB_ variables. Without a
+// configuration file ISB_BASE_URL is required.
+func NewClientFromEnv() (*Client, error) {
+	return NewClientFromProfile("")
+}
@@ -0,0 +1,178 @@
+package isbclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfig = `defaultProfile: dev
+profiles:
+  dev:
+    baseUrl: %s
+    auth:
+      method: secret
+      secret: env:ISB_TEST_JWT_SECRET
+      user:
+        email: ci@example.com
+        roles: [Manager]
+    pageSize: 25
+    timeout: 30s
+    retry:
+      maxAttempts: 4
+  prod:
+    baseUrl: https://prod.example.com/api
+    auth:
+      method: token
+      token: prod-token
+`
+
+func writeConfig(t *testing.T, baseURL string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(strings.Replace(testConfig, "%s", baseURL, 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(ConfigFileEnvVar, path)
+	for _, name := range []string{ProfileEnvVar, BaseURLEnvVar, AuthMethodEnvVar, SecretEnvVar, UserEmailEnvVar, PageSizeEnvVar, TimeoutEnvVar, RetryMaxAttemptsEnvVar, TokenEnvVar} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	writeConfig(t, "https://dev.example.com/api")
+
+	dev, err := LoadProfile("")
+	if err != nil {
+		t.Fatalf("LoadProfile error: %v", err)
+	}
+	if dev.Name != "dev" || dev.BaseURL != "https://dev.example.com/api" || dev.PageSize != 25 || dev.Timeout != 30*time.Second || dev.Retry.MaxAttempts != 4 {
+		t.Errorf("unexpected default profile: %+v", dev)
+	}
+	if dev.Auth.Method != AuthMethodSecret || dev.Auth.Secret != "env:ISB_TEST_JWT_SECRET" || dev.Auth.User.Roles[0] != RoleManager {
+		t.Errorf("unexpected auth: %+v", dev.Auth)
+	}
+
+	t.Setenv(ProfileEnvVar, "prod")
+	t.Setenv(BaseURLEnvVar, "https://override.example.com/api")
+	t.Setenv(PageSizeEnvVar, "10")
+	t.Setenv(TimeoutEnvVar, "5s")
+	t.Setenv(UserRolesEnvVar, "Admin, User,")
+	prod, err := LoadProfile("")
+	if err != nil {
+		t.Fatalf("LoadProfile error: %v", err)
+	}
+	if prod.Name != "prod" || prod.BaseURL != "https://override.example.com/api" || prod.PageSize != 10 || prod.Timeout != 5*time.Second || prod.Auth.Token != "prod-token" {
+		t.Errorf("expected environment variables to override the prod profile, got %+v", prod)
+	}
+	if !reflect.DeepEqual(prod.Auth.User.Roles, []string{RoleAdmin, RoleUser}) {
+		t.Errorf("expected roles from the environment, got %v", prod.Auth.User.Roles)
+	}
+
+	if _, err := LoadProfile("staging"); err == nil {
+		t.Error("expected an error for a missing profile")
+	}
+	t.Setenv(PageSizeEnvVar, "many")
+	if _, err := LoadProfile("dev"); err == nil {
+		t.Error("expected an error for an invalid page size")
+	}
+}
+
+func TestNewClientFromProfile(t *testing.T) {
+	var gotPageSize, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPageSize, gotAuth = r.URL.Query().Get("pageSize"), r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"result":[]}}`))
+	}))
+	defer server.Close()
+	writeConfig(t, server.URL)
+	t.Setenv("ISB_TEST_JWT_SECRET", "secret")
+
+	client, err := NewClientFromProfile("dev")
+	if err != nil {
+		t.Fatalf("NewClientFromProfile error: %v", err)
+	}
+	if client.HTTPClient.Timeout != 30*time.Second || client.DefaultPageSize != 25 {
+		t.Errorf("unexpected client settings: timeout %v, page size %d", client.HTTPClient.Timeout, client.DefaultPageSize)
+	}
+	if _, err := client.GetLeases(context.Background(), &GetLeasesRequest{}); err != nil {
+		t.Fatalf("GetLeases error: %v", err)
+	}
+	if gotPageSize != "25" {
+		t.Errorf("expected the default page size to be sent, got %q", gotPageSize)
+	}
+	claims, err := VerifyJWT(strings.TrimPrefix(gotAuth, "Bearer "), VerifyOptions{Secret: "secret"})
+	if err != nil || claims.User.Email != "ci@example.com" || claims.User.Roles[0] != RoleManager {
+		t.Errorf("expected a token signed for the profile user, got %+v %v", claims, err)
+	}
+	if _, err := client.GetLeases(context.Background(), &GetLeasesRequest{PageSize: "5"}); err != nil || gotPageSize != "5" {
+		t.Errorf("expected an explicit page size to win, got %q %v", gotPageSize, err)
+	}
+
+	prod, err := NewClientFromProfile("prod")
+	if err != nil || prod.Token != "prod-token" {
+		t.Errorf("expected the prod token, got %v", err)
+	}
+
+	noRoles := &Profile{Name: "ci", BaseURL: server.URL, Auth: ProfileAuth{
+		Method: AuthMethodSecret,
+		Secret: "env:ISB_TEST_JWT_SECRET",
+		User:   ProfileUser{Email: "ci@example.com"},
+	}}
+	if _, err := noRoles.NewClient(ProfileClientOptions{}); err == nil || !strings.Contains(err.Error(), "role") {
+		t.Errorf("expected an error for a user without roles, got %v", err)
+	}
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	t.Setenv(ConfigFileEnvVar, filepath.Join(t.TempDir(), "missing.yaml"))
+	t.Setenv(ProfileEnvVar, "")
+	t.Setenv(BaseURLEnvVar, "")
+	t.Setenv(AuthMethodEnvVar, AuthMethodToken)
+	t.Setenv(TokenEnvVar, "env-token")
+	if _, err := NewClientFromEnv(); err == nil {
+		t.Error("expected an error without a base URL")
+	}
+
+	t.Setenv(BaseURLEnvVar, "https://isb.example.com/api/")
+	client, err := NewClientFromEnv()
+	if err != nil {
+		t.Fatalf("NewClientFromEnv error: %v", err)
+	}
+	if client.BaseURL != "https://isb.example.com/api" || client.Token != "env-token" {
+		t.Errorf("unexpected client: %s %q", client.BaseURL, client.Token)
+	}
+}
+
+func TestParseSecretProvider(t *testing.T) {
+	t.Setenv("ISB_TEST_JWT_SECRET", "from-env")
+	provider, err := ParseSecretProvider("env:ISB_TEST_JWT_SECRET", 0, nil)
+	if err != nil {
+		t.Fatalf("ParseSecretProvider error: %v", err)
+	}
+	if secret, _ := provider.Secret(context.Background()); secret != "from-env" {
+		t.Errorf("expected from-env, got %q", secret)
+	}
+
+	fetch := func(_ context.Context, id string) (string, error) { return "sm:" + id, nil }
+	provider, err = ParseSecretProvider("secretsmanager:isb-jwt", time.Minute, fetch)
+	if err != nil {
+		t.Fatalf("ParseSecretProvider error: %v", err)
+	}
+	if secret, _ := provider.Secret(context.Background()); secret != "sm:isb-jwt" {
+		t.Errorf("expected sm:isb-jwt, got %q", secret)
+	}
+
+	for _, ref := range []string{"plain-secret", "vault:path", "command: ", "secretsmanager:isb-jwt"} {
+		if _, err := ParseSecretProvider(ref, 0, nil); err == nil {
+			t.Errorf("ParseSecretProvider(%q): expected an error", ref)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+module github.com/gymshark/aws-go-isb-client/promisb
+
+go 1.24.5
+
+require (
+	github.com/gymshark/aws-go-isb-client v0.0.0-00010101000000-000000000000
+	github.com/prometheus/client_golang v1.23.2
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/golang-jwt/jwt/v5 v5.3.0 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_model v0.6.2 // indirect
+	github.com/prometheus/common v0.66.1 // indirect
+	github.com/prometheus/procfs v0.16.1 // indirect
+	go.yaml.in/yaml/v2 v2.4.2 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	google.golang.org/protobuf v1.36.8 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The root module has no tagged release yet, so this module is built against the copy in
+// this repository. Release order: tag the root module, require that tag above in place of
+// the zero version, remove this replace, then tag promisb/vX.Y.Z.
+replace github.com/gymshark/aws-go-isb-client => ../
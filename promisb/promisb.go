@@ -0,0 +1,117 @@
+// Package promisb provides a Prometheus implementation of isbclient.Metrics.
+//
+// It lives in its own module so that users of the client who do not collect metrics do not
+// depend on the Prometheus client library.
+//
+//	metrics := promisb.New(promisb.Opts{})
+//	prometheus.MustRegister(metrics)
+//	client.Use(isbclient.RecordMetrics(metrics), isbclient.Retry(isbclient.RetryPolicy{}))
+package promisb
+
+import (
+	isbclient "github.com/gymshark/aws-go-isb-client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Opts configures the collected metrics. Zero fields take the documented defaults.
+type Opts struct {
+	Namespace   string            // metric name prefix (default "isb_client")
+	Buckets     []float64         // latency histogram buckets in seconds (default prometheus.DefBuckets)
+	ConstLabels prometheus.Labels // labels added to every metric
+}
+
+// Metrics implements isbclient.Metrics and prometheus.Collector. It exports:
+//
+//   - <namespace>_requests_total{operation,method,status_class}
+//   - <namespace>_request_duration_seconds{operation,method}
+//   - <namespace>_retries_total{operation}
+//   - <namespace>_pages_total{operation}
+//   - <namespace>_errors_total{operation,category}
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	retries  *prometheus.CounterVec
+	pages    *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+}
+
+var _ isbclient.Metrics = (*Metrics)(nil)
+
+// New returns Metrics configured by opts. It must be registered with a prometheus.Registerer
+// to be exported.
+func New(opts Opts) *Metrics {
+	if opts.Namespace == "" {
+		opts.Namespace = "isb_client"
+	}
+	if opts.Buckets == nil {
+		opts.Buckets = prometheus.DefBuckets
+	}
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "requests_total",
+			Help:        "ISB client calls by operation, HTTP method and response status class.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"operation", "method", "status_class"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Name:        "request_duration_seconds",
+			Help:        "ISB client call latency, including retries.",
+			Buckets:     opts.Buckets,
+			ConstLabels: opts.ConstLabels,
+		}, []string{"operation", "method"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "retries_total",
+			Help:        "ISB client retries by operation.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"operation"}),
+		pages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "pages_total",
+			Help:        "Pages fetched by paginating ISB client operations.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "errors_total",
+			Help:        "Failed ISB client calls by operation and error category.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"operation", "category"}),
+	}
+}
+
+// ObserveCall implements isbclient.Metrics.
+func (m *Metrics) ObserveCall(o isbclient.CallObservation) {
+	m.requests.WithLabelValues(o.Operation, o.Method, o.StatusClass).Inc()
+	m.duration.WithLabelValues(o.Operation, o.Method).Observe(o.Duration.Seconds())
+	if o.Retries > 0 {
+		m.retries.WithLabelValues(o.Operation).Add(float64(o.Retries))
+	}
+	if o.ErrorCategory != "" {
+		m.errors.WithLabelValues(o.Operation, o.ErrorCategory).Inc()
+	}
+}
+
+// ObservePage implements isbclient.Metrics.
+func (m *Metrics) ObservePage(operation string) {
+	m.pages.WithLabelValues(operation).Inc()
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.duration.Describe(ch)
+	m.retries.Describe(ch)
+	m.pages.Describe(ch)
+	m.errors.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.duration.Collect(ch)
+	m.retries.Collect(ch)
+	m.pages.Collect(ch)
+	m.errors.Collect(ch)
+}
@@ -0,0 +1,72 @@
+package promisb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	isbclient "github.com/gymshark/aws-go-isb-client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/leases" && r.URL.Query().Get("pageIdentifier") == "":
+			_, _ = w.Write([]byte(`{"status":"success","data":{"result":[{"uuid":"a"}],"nextPageIdentifier":"2"}}`))
+		case r.URL.Path == "/leases":
+			_, _ = w.Write([]byte(`{"status":"success","data":{"result":[{"uuid":"b"}],"nextPageIdentifier":""}}`))
+		case hits.Add(1) == 1:
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`{"status":"error","message":"try again"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"status":"fail","data":{"errors":[{"message":"not found"}]}}`))
+		}
+	}))
+	defer server.Close()
+
+	metrics := New(Opts{})
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(metrics)
+	client := isbclient.NewClient(server.URL, "token")
+	client.Use(isbclient.RecordMetrics(metrics), isbclient.Retry(isbclient.RetryPolicy{BaseDelay: time.Millisecond}))
+
+	if _, err := client.FetchAllLeases(context.Background(), &isbclient.GetLeasesRequest{}); err != nil {
+		t.Fatalf("FetchAllLeases error: %v", err)
+	}
+	if _, err := client.GetLeaseTemplateByID(context.Background(), &isbclient.GetLeaseTemplateByIDRequest{LeaseTemplateID: "tpl"}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	expected := `
+# HELP isb_client_requests_total ISB client calls by operation, HTTP method and response status class.
+# TYPE isb_client_requests_total counter
+isb_client_requests_total{method="",operation="FetchAllLeases",status_class="none"} 1
+isb_client_requests_total{method="GET",operation="GetLeaseTemplateByID",status_class="4xx"} 1
+isb_client_requests_total{method="GET",operation="GetLeases",status_class="2xx"} 2
+# HELP isb_client_retries_total ISB client retries by operation.
+# TYPE isb_client_retries_total counter
+isb_client_retries_total{operation="GetLeaseTemplateByID"} 1
+# HELP isb_client_pages_total Pages fetched by paginating ISB client operations.
+# TYPE isb_client_pages_total counter
+isb_client_pages_total{operation="FetchAllLeases"} 2
+# HELP isb_client_errors_total Failed ISB client calls by operation and error category.
+# TYPE isb_client_errors_total counter
+isb_client_errors_total{category="lease_template_not_found",operation="GetLeaseTemplateByID"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"isb_client_requests_total", "isb_client_retries_total", "isb_client_pages_total", "isb_client_errors_total"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(metrics, "isb_client_request_duration_seconds"); n != 3 {
+		t.Errorf("expected 3 latency series, got %d", n)
+	}
+}
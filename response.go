@@ -0,0 +1,197 @@
+package isbclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxResponseSize is the response body size limit used when Client.MaxResponseSize is zero.
+const DefaultMaxResponseSize int64 = 32 << 20 // 32 MiB
+
+const (
+	// sniffLen is the number of leading body bytes inspected before decoding.
+	sniffLen = 512
+	// drainLen bounds how much trailing body is read to let the connection be reused.
+	drainLen = 4 << 10
+)
+
+var (
+	// bodyReaderPool holds buffered readers used to sniff and stream response bodies.
+	bodyReaderPool = sync.Pool{New: func() any { return new(limitedBodyReader) }}
+	// errorBodyPool holds buffers used to read error response bodies.
+	errorBodyPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+)
+
+// requestIDHeaders are the response headers checked, in order, for a request ID.
+var requestIDHeaders = []string{"X-Amzn-Requestid", "X-Amz-Request-Id", "X-Request-Id", "X-Amz-Cf-Id"}
+
+// ResponseMetadata describes the HTTP exchange behind an API call.
+type ResponseMetadata struct {
+	HTTPStatus int           // HTTP status code
+	Header     http.Header   // response headers
+	RequestID  string        // request ID reported by API Gateway or CloudFront, if any
+	Duration   time.Duration // time from sending the request to decoding the response
+	Status     string        // envelope status ("success", "fail" or "error"), if decoded
+}
+
+// Response is the decoded data of an API call together with its ResponseMetadata.
+type Response[T any] struct {
+	Data T
+	ResponseMetadata
+}
+
+type responseMetadataKey struct{}
+
+// WithResponse runs call, typically a single client method, and returns its result together
+// with the metadata of the response it received. Client method signatures are unchanged; the
+// metadata is collected through the context passed to call.
+//
+// When call makes several requests (for example FetchAllLeases or PatchLeaseTemplate) the
+// metadata describes the last one. The Response is returned alongside any error so that status
+// codes and request IDs of failed calls remain available.
+//
+//	resp, err := isbclient.WithResponse(ctx, func(ctx context.Context) (*isbclient.GetLeasesResponse, error) {
+//		return client.GetLeases(ctx, req)
+//	})
+//	log.Printf("request %s took %s", resp.RequestID, resp.Duration)
+func WithResponse[T any](ctx context.Context, call func(context.Context) (T, error)) (*Response[T], error) {
+	resp := &Response[T]{}
+	data, err := call(context.WithValue(ctx, responseMetadataKey{}, &resp.ResponseMetadata))
+	resp.Data = data
+	return resp, err
+}
+
+// recordResponse stores the metadata of resp in the ResponseMetadata and Call carried by ctx, if any.
+func recordResponse(ctx context.Context, resp *http.Response, started time.Time, status string) {
+	captured, _ := ctx.Value(responseMetadataKey{}).(*ResponseMetadata)
+	call := callFromContext(ctx)
+	if captured == nil && call == nil {
+		return
+	}
+	meta := ResponseMetadata{
+		HTTPStatus: resp.StatusCode,
+		Header:     resp.Header,
+		RequestID:  requestID(resp.Header),
+		Duration:   time.Since(started),
+		Status:     status,
+	}
+	if captured != nil {
+		*captured = meta
+	}
+	if call != nil {
+		call.Response = &meta
+	}
+}
+
+// requestID returns the first request ID header present in h.
+func requestID(h http.Header) string {
+	for _, name := range requestIDHeaders {
+		if id := h.Get(name); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// limitedBodyReader streams a response body through a buffered reader and fails with a
+// *ResponseTooLargeError once more than the configured limit would be read.
+type limitedBodyReader struct {
+	*bufio.Reader
+	src limitReader
+}
+
+// acquireBodyReader returns a pooled reader over body enforcing limit (-1 for none).
+func acquireBodyReader(body io.Reader, limit int64) *limitedBodyReader {
+	r := bodyReaderPool.Get().(*limitedBodyReader)
+	r.src = limitReader{r: body, limit: limit, remaining: limit}
+	if r.Reader == nil {
+		r.Reader = bufio.NewReaderSize(&r.src, sniffLen)
+	} else {
+		r.Reader.Reset(&r.src)
+	}
+	return r
+}
+
+// releaseBodyReader returns r to the pool. r must not be used afterwards.
+func releaseBodyReader(r *limitedBodyReader) {
+	r.src = limitReader{}
+	bodyReaderPool.Put(r)
+}
+
+// limitReader reads from r until more than limit bytes would be returned.
+type limitReader struct {
+	r         io.Reader
+	limit     int64 // -1 for no limit
+	remaining int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.limit < 0 {
+		return l.r.Read(p)
+	}
+	if l.remaining <= 0 {
+		// Only fail if the body really continues past the limit.
+		var probe [1]byte
+		if n, err := l.r.Read(probe[:]); n == 0 {
+			return 0, err
+		}
+		return 0, &ResponseTooLargeError{Limit: l.limit}
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// checkContentLength rejects responses that declare a body larger than limit up front.
+func checkContentLength(resp *http.Response, limit int64) error {
+	if limit >= 0 && resp.ContentLength > limit {
+		return &ResponseTooLargeError{Limit: limit}
+	}
+	return nil
+}
+
+// isJSONResponse reports whether the response is JSON or empty by sniffing the first bytes
+// of body, without consuming them. For non-JSON responses it also returns those bytes.
+func isJSONResponse(resp *http.Response, body *limitedBodyReader) (bool, string) {
+	head, _ := body.Peek(sniffLen)
+	// Body is empty, no need to check Content-Type
+	if len(head) == 0 {
+		return true, ""
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "application/json") {
+		return false, string(head)
+	}
+	return true, ""
+}
+
+// decodeAPIErrorFrom reads an error response body from body into a pooled buffer and decodes it,
+// returning its envelope status alongside the error.
+func decodeAPIErrorFrom(reqBody []byte, resp *http.Response, body io.Reader) (string, error) {
+	buf := errorBodyPool.Get().(*bytes.Buffer)
+	defer func() {
+		buf.Reset()
+		errorBodyPool.Put(buf)
+	}()
+	if _, err := buf.ReadFrom(body); err != nil {
+		if tooLarge, ok := err.(*ResponseTooLargeError); ok {
+			return "", tooLarge
+		}
+	}
+	return decodeAPIError(reqBody, resp, buf.Bytes())
+}
+
+// drainBody discards a bounded amount of unread body so the connection can be reused.
+func drainBody(body io.Reader) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, drainLen))
+}
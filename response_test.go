@@ -0,0 +1,227 @@
+package isbclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Amzn-RequestId", "req-123")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"result":[{"uuid":"lease-1"}],"nextPageIdentifier":""}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	resp, err := WithResponse(context.Background(), func(ctx context.Context) (*GetLeasesResponse, error) {
+		return client.GetLeases(ctx, nil)
+	})
+	if err != nil {
+		t.Fatalf("GetLeases error: %v", err)
+	}
+	if len(resp.Data.Leases) != 1 || resp.Data.Leases[0].UUID != "lease-1" {
+		t.Errorf("unexpected data: %+v", resp.Data)
+	}
+	if resp.HTTPStatus != http.StatusOK {
+		t.Errorf("expected HTTP status 200, got %d", resp.HTTPStatus)
+	}
+	if resp.RequestID != "req-123" {
+		t.Errorf("expected request ID req-123, got %q", resp.RequestID)
+	}
+	if resp.Status != "success" {
+		t.Errorf("expected envelope status success, got %q", resp.Status)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("expected response headers, got %v", resp.Header)
+	}
+	if resp.Duration <= 0 {
+		t.Errorf("expected a positive duration, got %s", resp.Duration)
+	}
+}
+
+func TestWithResponse_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req-404")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"status":"fail","data":{"errors":[{"message":"no such lease"}]}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	resp, err := WithResponse(context.Background(), func(ctx context.Context) (*GetLeaseByIDResponse, error) {
+		return client.GetLeaseByID(ctx, &GetLeaseByIDRequest{LeaseID: "missing"})
+	})
+	if _, ok := err.(*LeaseNotFoundError); !ok {
+		t.Fatalf("expected LeaseNotFoundError, got %T %v", err, err)
+	}
+	if resp.HTTPStatus != http.StatusNotFound || resp.RequestID != "req-404" || resp.Status != "fail" {
+		t.Errorf("expected metadata of the failed call, got %+v", resp.ResponseMetadata)
+	}
+}
+
+func TestWithResponse_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"status":"error","message":"boom"}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	resp, err := WithResponse(context.Background(), func(ctx context.Context) (*GetLeaseByIDResponse, error) {
+		return client.GetLeaseByID(ctx, &GetLeaseByIDRequest{LeaseID: "lease-1"})
+	})
+	if _, ok := err.(*ServerError); !ok {
+		t.Fatalf("expected ServerError, got %T %v", err, err)
+	}
+	if resp.HTTPStatus != http.StatusInternalServerError || resp.Status != "error" {
+		t.Errorf("expected the envelope status of the error response, got %+v", resp.ResponseMetadata)
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	page := leasePage(200, "")
+	tests := []struct {
+		name    string
+		chunked bool
+	}{
+		{"declared content length", false},
+		{"chunked", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if !tt.chunked {
+					w.Header().Set("Content-Length", fmt.Sprint(len(page)))
+				}
+				_, _ = w.Write(page)
+			}))
+			defer server.Close()
+			client := NewClient(server.URL, "token")
+			client.MaxResponseSize = 1024
+
+			_, err := client.GetLeases(context.Background(), nil)
+			var tooLarge *ResponseTooLargeError
+			if !errors.As(err, &tooLarge) || tooLarge.Limit != 1024 {
+				t.Fatalf("expected ResponseTooLargeError, got %T %v", err, err)
+			}
+
+			client.MaxResponseSize = int64(len(page))
+			resp, err := client.GetLeases(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("expected a body of exactly the limit to be accepted, got %v", err)
+			}
+			if len(resp.Leases) != 200 {
+				t.Errorf("expected 200 leases, got %d", len(resp.Leases))
+			}
+		})
+	}
+}
+
+func TestNonJSONResponse_IncludesBodyHead(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte("<html>" + strings.Repeat("x", 4096) + "</html>"))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	_, err := client.GetLeases(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "non-JSON response (text/html): <html>xxx") {
+		t.Fatalf("expected non-JSON response error with body head, got %v", err)
+	}
+	if len(err.Error()) > sniffLen+200 {
+		t.Errorf("expected only the first %d body bytes in the error, got %d bytes", sniffLen, len(err.Error()))
+	}
+}
+
+// leasePage returns an encoded GET /leases response holding n leases.
+func leasePage(n int, next string) []byte {
+	leases := make([]Lease, n)
+	for i := range leases {
+		leases[i] = Lease{
+			UUID:                      fmt.Sprintf("12345678-90ab-cdef-1234-%012d", i),
+			UserEmail:                 fmt.Sprintf("user%d@example.com", i),
+			Status:                    LeaseStatusActive,
+			OriginalLeaseTemplateUuid: "12345678-90ab-cdef-1234-567890abcdef",
+			OriginalLeaseTemplateName: "Example Template",
+			LeaseDurationInHours:      168,
+			MaxSpend:                  100,
+			BudgetThresholds:          []BudgetThreshold{{DollarsSpent: 50, Action: "ALERT"}, {DollarsSpent: 90, Action: "FREEZE_ACCOUNT"}},
+			DurationThresholds:        []DurationThreshold{{HoursRemaining: 24, Action: "ALERT"}},
+			Comments:                  "benchmark lease",
+			AwsAccountId:              "123456789012",
+			StartDate:                 "2025-01-15T08:30:00Z",
+			ExpirationDate:            "2025-01-22T08:30:00Z",
+			TotalCostAccrued:          45.67,
+			Meta:                      MetaData{CreatedTime: "2025-01-15T08:30:00Z", LastEditTime: "2025-01-15T08:30:00Z", SchemaVersion: "1"},
+		}
+	}
+	b, _ := json.Marshal(map[string]interface{}{
+		"status": "success",
+		"data":   map[string]interface{}{"result": leases, "nextPageIdentifier": next},
+	})
+	return b
+}
+
+func BenchmarkGetLeases_LargePage(b *testing.B) {
+	for _, n := range []int{100, 1000, 5000} {
+		b.Run(fmt.Sprintf("leases=%d", n), func(b *testing.B) {
+			page := leasePage(n, "")
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write(page)
+			}))
+			defer server.Close()
+			client := NewClient(server.URL, "token")
+			b.SetBytes(int64(len(page)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := client.GetLeases(context.Background(), nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFetchAllLeases(b *testing.B) {
+	const pages, perPage = 10, 500
+	bodies := make([][]byte, pages)
+	for i := range bodies {
+		next := ""
+		if i < pages-1 {
+			next = fmt.Sprint(i + 1)
+		}
+		bodies[i] = leasePage(perPage, next)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var page int
+		fmt.Sscan(r.URL.Query().Get("pageIdentifier"), &page)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(bodies[page])
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp, err := client.FetchAllLeases(context.Background(), &GetLeasesRequest{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(resp.Leases) != pages*perPage {
+			b.Fatalf("expected %d leases, got %d", pages*perPage, len(resp.Leases))
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package isbclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// UserClaimsBuilder builds UserClaims with any display name, several roles and custom members.
+// Errors are collected and returned by Build.
+type UserClaimsBuilder struct {
+	claims UserClaims
+	errs   []error
+}
+
+// NewUserClaimsBuilder returns a builder for the user with the given email, which is also used
+// as the user name and, unless DisplayName is called, the display name.
+func NewUserClaimsBuilder(email string) *UserClaimsBuilder {
+	b := &UserClaimsBuilder{claims: UserClaims{DisplayName: email, UserName: email, Email: email}}
+	if email == "" {
+		b.errs = append(b.errs, errors.New("email is required"))
+	}
+	return b
+}
+
+// DisplayName sets the display name.
+func (b *UserClaimsBuilder) DisplayName(name string) *UserClaimsBuilder {
+	b.claims.DisplayName = name
+	return b
+}
+
+// UserName sets the user name.
+func (b *UserClaimsBuilder) UserName(name string) *UserClaimsBuilder {
+	b.claims.UserName = name
+	return b
+}
+
+// Roles adds roles, which must be RoleAdmin, RoleManager or RoleUser.
+func (b *UserClaimsBuilder) Roles(roles ...string) *UserClaimsBuilder {
+	for _, role := range roles {
+		switch {
+		case !slices.Contains(allRoles, role):
+			b.errs = append(b.errs, fmt.Errorf("unknown role %q", role))
+		case !slices.Contains(b.claims.Roles, role):
+			b.claims.Roles = append(b.claims.Roles, role)
+		}
+	}
+	return b
+}
+
+// Field sets a custom member of the user claims to the JSON encoding of value.
+func (b *UserClaimsBuilder) Field(name string, value any) *UserClaimsBuilder {
+	type plain UserClaims
+	if slices.Contains(jsonFieldNames(reflect.TypeOf(plain{})), name) {
+		b.errs = append(b.errs, fmt.Errorf("field %q is a standard claim", name))
+		return b
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("field %q: %w", name, err))
+		return b
+	}
+	if b.claims.custom == nil {
+		b.claims.custom = map[string]json.RawMessage{}
+	}
+	b.claims.custom[name] = raw
+	return b
+}
+
+// Build returns the claims, or the errors of the builder calls joined. At least one role is
+// required.
+func (b *UserClaimsBuilder) Build() (UserClaims, error) {
+	errs := b.errs
+	if len(b.claims.Roles) == 0 {
+		errs = append(errs, errors.New("at least one role is required"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return UserClaims{}, err
+	}
+	claims := b.claims
+	claims.Roles = slices.Clone(claims.Roles)
+	claims.custom = maps.Clone(claims.custom)
+	return claims, nil
+}
+
+var (
+	allRoles     = []string{RoleAdmin, RoleManager, RoleUser}
+	managerRoles = []string{RoleAdmin, RoleManager}
+	adminRoles   = []string{RoleAdmin}
+)
+
+// RolePermissions maps client operations to the roles the ISB allows to perform them. Users
+// may request and view leases and view lease templates; managers may also review and manage
+// leases and lease templates; only admins may manage accounts. Operations not listed, such as
+// Do and CreateLeaseAsUser, are not checked.
+var RolePermissions = map[string][]string{
+	"GetLoginStatus":    allRoles,
+	"GetConfigurations": allRoles,
+
+	"GetLeases":      allRoles,
+	"FetchAllLeases": allRoles,
+	"GetLeaseByID":   allRoles,
+	"CreateLease":    allRoles,
+	"ReviewLease":    managerRoles,
+	"UpdateLease":    managerRoles,
+	"FreezeLease":    managerRoles,
+	"TerminateLease": managerRoles,
+
+	"GetLeaseTemplates":      allRoles,
+	"FetchAllLeaseTemplates": allRoles,
+	"GetLeaseTemplateByID":   allRoles,
+	"CreateLeaseTemplate":    managerRoles,
+	"UpdateLeaseTemplate":    managerRoles,
+	"PatchLeaseTemplate":     managerRoles,
+	"DeleteLeaseTemplate":    managerRoles,
+
+	"GetAccounts":                  adminRoles,
+	"FetchAllAccounts":             adminRoles,
+	"GetAccountByID":               adminRoles,
+	"GetUnregisteredAccounts":      adminRoles,
+	"FetchAllUnregisteredAccounts": adminRoles,
+	"RegisterAccount":              adminRoles,
+	"EjectAccount":                 adminRoles,
+	"RetryCleanup":                 adminRoles,
+}
+
+// RoleAllows reports whether a user holding roles may perform op according to RolePermissions.
+// Operations not listed are allowed.
+func RoleAllows(op string, roles []string) bool {
+	allowed, ok := RolePermissions[op]
+	return !ok || slices.ContainsFunc(roles, func(r string) bool { return slices.Contains(allowed, r) })
+}
+
+// RolePreflight returns middleware that reads the roles from the token a call's request is
+// sent with (Client.Token of c, or the token of an Authenticate middleware registered anywhere
+// in the chain) and fails calls those roles may not perform with a *ForbiddenForRoleError,
+// without calling the API. Calls whose token cannot be decoded are sent and the API decides.
+func RolePreflight(c *Client) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			if _, checked := RolePermissions[call.Operation]; !checked {
+				return next(ctx, call)
+			}
+			// The token is only known once every middleware has run, so it is checked by send.
+			call.checkToken = func(token string) error {
+				claims, err := ParseJWT(token)
+				if err != nil || RoleAllows(call.Operation, claims.User.Roles) {
+					return nil
+				}
+				return &ForbiddenForRoleError{
+					Op:      call.Operation,
+					Roles:   claims.User.Roles,
+					Allowed: RolePermissions[call.Operation],
+				}
+			}
+			return next(ctx, call)
+		}
+	}
+}
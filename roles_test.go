@@ -0,0 +1,132 @@
+package isbclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUserClaimsBuilder(t *testing.T) {
+	claims, err := NewUserClaimsBuilder("jane@example.com").
+		DisplayName("Jane Doe").
+		Roles(RoleManager, RoleUser, RoleManager).
+		Field("team", "data-science").
+		Field("costCentre", 42).
+		Build()
+	if err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	if claims.DisplayName != "Jane Doe" || claims.UserName != "jane@example.com" || !reflect.DeepEqual(claims.Roles, []string{RoleManager, RoleUser}) {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	// Custom fields are signed into the token and survive parsing.
+	tokenStr, err := GenerateJWT(claims, "secret", time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateJWT error: %v", err)
+	}
+	parsed, err := VerifyJWT(tokenStr, VerifyOptions{Secret: "secret"})
+	if err != nil {
+		t.Fatalf("VerifyJWT error: %v", err)
+	}
+	want := map[string]json.RawMessage{"team": json.RawMessage(`"data-science"`), "costCentre": json.RawMessage(`42`)}
+	if got := parsed.User.CustomFields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected custom fields %s, got %s", want, got)
+	}
+	if parsed.User.Email != "jane@example.com" || len(parsed.User.Roles) != 2 {
+		t.Errorf("unexpected parsed claims: %+v", parsed.User)
+	}
+}
+
+func TestUserClaimsBuilder_Errors(t *testing.T) {
+	tests := map[string]*UserClaimsBuilder{
+		"no email":       NewUserClaimsBuilder("").Roles(RoleUser),
+		"no roles":       NewUserClaimsBuilder("jane@example.com"),
+		"unknown role":   NewUserClaimsBuilder("jane@example.com").Roles("Owner"),
+		"standard field": NewUserClaimsBuilder("jane@example.com").Roles(RoleUser).Field("roles", []string{RoleAdmin}),
+		"bad value":      NewUserClaimsBuilder("jane@example.com").Roles(RoleUser).Field("ch", make(chan int)),
+	}
+	for name, b := range tests {
+		if _, err := b.Build(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestRolePermissions_CoverSpecOperations(t *testing.T) {
+	for op := range specOperations {
+		if _, ok := RolePermissions[op]; !ok {
+			t.Errorf("operation %s has no role permissions", op)
+		}
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		op    string
+		roles []string
+		want  bool
+	}{
+		{"CreateLease", []string{RoleUser}, true},
+		{"ReviewLease", []string{RoleUser}, false},
+		{"ReviewLease", []string{RoleUser, RoleManager}, true},
+		{"RegisterAccount", []string{RoleManager}, false},
+		{"RegisterAccount", []string{RoleAdmin}, true},
+		{"Do", nil, true},
+	}
+	for _, tt := range tests {
+		if got := RoleAllows(tt.op, tt.roles); got != tt.want {
+			t.Errorf("RoleAllows(%s, %v) = %v, want %v", tt.op, tt.roles, got, tt.want)
+		}
+	}
+}
+
+func TestRolePreflight(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"uuid":"lease-1"}}`))
+	}))
+	defer server.Close()
+
+	token, _ := GenerateJWT(NewUserUserClaims("user@example.com"), "secret", time.Hour)
+	client := NewClient(server.URL, token)
+	client.Use(RolePreflight(client))
+
+	err := client.ReviewLease(context.Background(), &ReviewLeaseRequest{LeaseID: "lease-1", Action: "Approve"})
+	var forbidden *ForbiddenForRoleError
+	if !errors.As(err, &forbidden) || forbidden.Op != "ReviewLease" || !reflect.DeepEqual(forbidden.Roles, []string{RoleUser}) {
+		t.Fatalf("expected ForbiddenForRoleError, got %T %v", err, err)
+	}
+	if requests.Load() != 0 {
+		t.Errorf("expected no request to be sent, got %d", requests.Load())
+	}
+	if _, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"}); err != nil {
+		t.Errorf("expected a permitted call to be sent, got %v", err)
+	}
+
+	// The token of an Authenticate middleware registered first is checked instead.
+	admin := NewClient(server.URL, "")
+	admin.Use(Authenticate(NewSecretTokenSource(SecretProviderFunc(func(context.Context) (string, error) { return "secret", nil }),
+		SecretTokenOptions{User: NewAdminUserClaims("admin@example.com")})), RolePreflight(admin))
+	if err := admin.ReviewLease(context.Background(), &ReviewLeaseRequest{LeaseID: "lease-1", Action: "Approve"}); err != nil {
+		t.Errorf("expected an admin to review leases, got %v", err)
+	}
+
+	// So is the token of an Authenticate middleware registered after it.
+	adminToken, _ := GenerateJWT(NewAdminUserClaims("admin@example.com"), "secret", time.Hour)
+	user := NewClient(server.URL, adminToken)
+	user.Use(RolePreflight(user), Authenticate(NewSecretTokenSource(SecretProviderFunc(func(context.Context) (string, error) { return "secret", nil }),
+		SecretTokenOptions{User: NewUserUserClaims("user@example.com")})))
+	requests.Store(0)
+	if err := user.ReviewLease(context.Background(), &ReviewLeaseRequest{LeaseID: "lease-1", Action: "Approve"}); !errors.As(err, &forbidden) || requests.Load() != 0 {
+		t.Errorf("expected the token sent, not Client.Token, to be checked, got %v and %d requests", err, requests.Load())
+	}
+}
@@ -0,0 +1,222 @@
+package isbclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecretProvider supplies the shared secret HS256 tokens are signed with, the value of the
+// secret referenced by the CloudFormation stack output JwtSecretArn.
+type SecretProvider interface {
+	Secret(ctx context.Context) (string, error)
+}
+
+// SecretProviderFunc adapts a function to a SecretProvider.
+type SecretProviderFunc func(ctx context.Context) (string, error)
+
+func (f SecretProviderFunc) Secret(ctx context.Context) (string, error) { return f(ctx) }
+
+// EnvSecret returns a SecretProvider reading the environment variable name.
+func EnvSecret(name string) SecretProvider {
+	return SecretProviderFunc(func(context.Context) (string, error) {
+		secret, ok := os.LookupEnv(name)
+		if !ok || secret == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	})
+}
+
+// fileSecret is the SecretProvider returned by FileSecret.
+type fileSecret struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	secret  string
+}
+
+// FileSecret returns a SecretProvider reading the file at path, with surrounding whitespace
+// removed. The file is read again whenever its modification time or size changes, so secrets
+// mounted by Kubernetes, which are replaced in place when updated, are picked up.
+func FileSecret(path string) SecretProvider {
+	return &fileSecret{path: path}
+}
+
+func (f *fileSecret) Secret(context.Context) (string, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.secret != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.secret, nil
+	}
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimSpace(string(b))
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", f.path)
+	}
+	f.secret, f.modTime, f.size = secret, info.ModTime(), info.Size()
+	return secret, nil
+}
+
+// CommandSecret returns a SecretProvider running name with args and using its standard
+// output, with surrounding whitespace removed, as the secret. This suits password managers
+// and vault CLIs.
+func CommandSecret(name string, args ...string) SecretProvider {
+	return SecretProviderFunc(func(ctx context.Context) (string, error) {
+		cmd := exec.CommandContext(ctx, name, args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", fmt.Errorf("%s: %w: %s", name, err, msg)
+			}
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		secret := strings.TrimSpace(string(out))
+		if secret == "" {
+			return "", fmt.Errorf("%s printed no secret", name)
+		}
+		return secret, nil
+	})
+}
+
+// SecretsManagerFetcher returns the SecretString of an AWS Secrets Manager secret. It is
+// typically a thin wrapper around the GetSecretValue call of the AWS SDK.
+type SecretsManagerFetcher func(ctx context.Context, secretID string) (string, error)
+
+// SecretsManagerSecret returns a SecretProvider fetching secretID, for example the
+// JwtSecretArn stack output, with fetch. The client does not depend on the AWS SDK; fetch
+// supplies the call, which also lets tests use a local stub.
+func SecretsManagerSecret(secretID string, fetch SecretsManagerFetcher) SecretProvider {
+	return SecretProviderFunc(func(ctx context.Context) (string, error) {
+		secret, err := fetch(ctx, secretID)
+		if err != nil {
+			return "", fmt.Errorf("fetch secret %s: %w", secretID, err)
+		}
+		if secret == "" {
+			return "", fmt.Errorf("secret %s is empty", secretID)
+		}
+		return secret, nil
+	})
+}
+
+// CachedSecret is a SecretProvider caching the secret of another provider for a TTL.
+type CachedSecret struct {
+	provider  SecretProvider
+	ttl       time.Duration
+	mu        sync.Mutex
+	secret    string
+	fetchedAt time.Time
+}
+
+// NewCachedSecret returns a CachedSecret caching the secret of provider for ttl (default 5
+// minutes).
+func NewCachedSecret(provider SecretProvider, ttl time.Duration) *CachedSecret {
+	return &CachedSecret{provider: provider, ttl: durationOr(ttl, 5*time.Minute)}
+}
+
+// Secret returns the cached secret, fetching it when the cache is empty or expired.
+func (c *CachedSecret) Secret(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.secret != "" && time.Since(c.fetchedAt) < c.ttl {
+		return c.secret, nil
+	}
+	secret, err := c.provider.Secret(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.secret, c.fetchedAt = secret, time.Now()
+	return secret, nil
+}
+
+// Invalidate empties the cache, so that the next call to Secret fetches the secret again.
+func (c *CachedSecret) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.secret = ""
+}
+
+// SecretTokenOptions configures a SecretTokenSource.
+type SecretTokenOptions struct {
+	// User is the identity tokens are signed for.
+	User UserClaims
+	// ExpiresIn is the lifetime of signed tokens (default 15 minutes). Tokens are reused
+	// until half of it has passed.
+	ExpiresIn time.Duration
+}
+
+// SecretTokenSource is a TokenRefresher signing HS256 tokens with the secret of a
+// SecretProvider. When a token is rejected, a CachedSecret provider is invalidated and the
+// secret fetched again; the request is retried if the secret changed.
+type SecretTokenSource struct {
+	provider  SecretProvider
+	opts      SecretTokenOptions
+	mu        sync.Mutex
+	secret    string
+	token     string
+	refreshAt time.Time
+}
+
+// NewSecretTokenSource returns a SecretTokenSource signing with the secret of provider.
+// Wrap provider in a CachedSecret unless it caches itself.
+func NewSecretTokenSource(provider SecretProvider, opts SecretTokenOptions) *SecretTokenSource {
+	opts.ExpiresIn = durationOr(opts.ExpiresIn, 15*time.Minute)
+	return &SecretTokenSource{provider: provider, opts: opts}
+}
+
+// Token returns a token signed with the current secret.
+func (s *SecretTokenSource) Token(ctx context.Context) (string, error) {
+	secret, err := s.provider.Secret(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && secret == s.secret && time.Now().Before(s.refreshAt) {
+		return s.token, nil
+	}
+	token, err := GenerateJWT(s.opts.User, secret, s.opts.ExpiresIn)
+	if err != nil {
+		return "", err
+	}
+	s.secret, s.token, s.refreshAt = secret, token, time.Now().Add(s.opts.ExpiresIn/2)
+	return token, nil
+}
+
+// Refresh fetches the secret again and reports whether it differs from the one rejected was
+// signed with.
+func (s *SecretTokenSource) Refresh(ctx context.Context, rejected string) (bool, error) {
+	if cached, ok := s.provider.(*CachedSecret); ok {
+		cached.Invalidate()
+	}
+	secret, err := s.provider.Secret(ctx)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rejected != s.token {
+		// Signed with an earlier secret; the current token may still be good.
+		return true, nil
+	}
+	if secret == s.secret {
+		return false, nil
+	}
+	s.token = ""
+	return true, nil
+}
@@ -0,0 +1,136 @@
+package isbclient
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEnvSecret(t *testing.T) {
+	t.Setenv("ISB_TEST_SECRET", "from-env")
+	if secret, err := EnvSecret("ISB_TEST_SECRET").Secret(context.Background()); err != nil || secret != "from-env" {
+		t.Errorf("expected from-env, got %q %v", secret, err)
+	}
+	if _, err := EnvSecret("ISB_TEST_SECRET_UNSET").Secret(context.Background()); err == nil {
+		t.Error("expected an error for an unset variable")
+	}
+}
+
+func TestFileSecret_ReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jwt-secret")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider := FileSecret(path)
+	if secret, err := provider.Secret(context.Background()); err != nil || secret != "first" {
+		t.Fatalf("expected first, got %q %v", secret, err)
+	}
+
+	// Kubernetes updates mounted secrets by swapping a symlink to a new file.
+	next := filepath.Join(dir, "next")
+	if err := os.WriteFile(next, []byte("second"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(next, time.Now().Add(time.Minute), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(next, path); err != nil {
+		t.Fatal(err)
+	}
+	if secret, err := provider.Secret(context.Background()); err != nil || secret != "second" {
+		t.Errorf("expected the changed file to be read, got %q %v", secret, err)
+	}
+}
+
+func TestCommandSecret(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	if secret, err := CommandSecret("sh", "-c", "echo from-command").Secret(context.Background()); err != nil || secret != "from-command" {
+		t.Errorf("expected from-command, got %q %v", secret, err)
+	}
+	if _, err := CommandSecret("sh", "-c", "echo denied >&2; exit 1").Secret(context.Background()); err == nil {
+		t.Error("expected an error for a failing command")
+	}
+}
+
+func TestSecretsManagerSecret(t *testing.T) {
+	stub := func(_ context.Context, secretID string) (string, error) {
+		if secretID != "arn:aws:secretsmanager:eu-west-1:123456789012:secret:isb-jwt" {
+			return "", errors.New("ResourceNotFoundException")
+		}
+		return "from-secrets-manager", nil
+	}
+	provider := SecretsManagerSecret("arn:aws:secretsmanager:eu-west-1:123456789012:secret:isb-jwt", stub)
+	if secret, err := provider.Secret(context.Background()); err != nil || secret != "from-secrets-manager" {
+		t.Errorf("expected from-secrets-manager, got %q %v", secret, err)
+	}
+	if _, err := SecretsManagerSecret("other", stub).Secret(context.Background()); err == nil {
+		t.Error("expected the fetch error to be returned")
+	}
+}
+
+func TestCachedSecret(t *testing.T) {
+	var fetches atomic.Int32
+	cached := NewCachedSecret(SecretProviderFunc(func(context.Context) (string, error) {
+		fetches.Add(1)
+		return "secret", nil
+	}), time.Hour)
+
+	for range 3 {
+		if _, err := cached.Secret(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("expected one fetch within the TTL, got %d", fetches.Load())
+	}
+	cached.Invalidate()
+	_, _ = cached.Secret(context.Background())
+	if fetches.Load() != 2 {
+		t.Errorf("expected a fetch after Invalidate, got %d", fetches.Load())
+	}
+}
+
+func TestSecretTokenSource_RefreshesOnUnauthorized(t *testing.T) {
+	var secret atomic.Value
+	secret.Store("rotated")
+	var requests atomic.Int32
+	server := secretServer(t, &secret, &requests)
+
+	var current atomic.Value
+	current.Store("stale")
+	var fetches atomic.Int32
+	provider := NewCachedSecret(SecretProviderFunc(func(context.Context) (string, error) {
+		fetches.Add(1)
+		return current.Load().(string), nil
+	}), time.Hour)
+	source := NewSecretTokenSource(provider, SecretTokenOptions{User: NewAdminUserClaims("admin@example.com")})
+	client := NewClient(server.URL, "")
+	client.Use(Authenticate(source))
+
+	// The cached secret is stale; the 401 refetches it and the request is retried.
+	_, _ = provider.Secret(context.Background())
+	current.Store("rotated")
+	if _, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"}); err != nil {
+		t.Fatalf("GetLeaseByID error: %v", err)
+	}
+	if requests.Load() != 2 || fetches.Load() != 2 {
+		t.Errorf("expected one refetch and one retry, got %d fetches and %d requests", fetches.Load(), requests.Load())
+	}
+
+	// An unchanged secret is not retried.
+	secret.Store("unknown")
+	requests.Store(0)
+	_, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"})
+	var unauthorized *UnauthorizedError
+	if !errors.As(err, &unauthorized) || requests.Load() != 1 {
+		t.Errorf("expected UnauthorizedError without a retry, got %v after %d requests", err, requests.Load())
+	}
+}
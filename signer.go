@@ -0,0 +1,289 @@
+package isbclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signer signs ISB tokens. Implementations are returned by NewHMACSigner, NewRSASigner,
+// NewECDSASigner and the PEM and JWKS loaders.
+type Signer interface {
+	// Algorithm returns the JWS algorithm, e.g. "HS256" or "ES256".
+	Algorithm() string
+	// KeyID returns the key ID set in the kid header of signed tokens, or "".
+	KeyID() string
+	// Sign returns the signed, compact serialisation of a token carrying claims.
+	Sign(claims jwt.Claims) (string, error)
+}
+
+// keySigner is a Signer using a jwt signing method and key.
+type keySigner struct {
+	method jwt.SigningMethod
+	key    any
+	kid    string
+}
+
+func (s *keySigner) Algorithm() string { return s.method.Alg() }
+
+func (s *keySigner) KeyID() string { return s.kid }
+
+func (s *keySigner) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.method, claims)
+	if s.kid != "" {
+		token.Header["kid"] = s.kid
+	}
+	return token.SignedString(s.key)
+}
+
+// NewHMACSigner returns a Signer using a shared secret with alg "HS256", "HS384" or "HS512".
+func NewHMACSigner(alg string, secret []byte, kid string) (Signer, error) {
+	var method jwt.SigningMethod
+	switch alg {
+	case "HS256":
+		method = jwt.SigningMethodHS256
+	case "HS384":
+		method = jwt.SigningMethodHS384
+	case "HS512":
+		method = jwt.SigningMethodHS512
+	default:
+		return nil, fmt.Errorf("unsupported HMAC algorithm %q", alg)
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("HMAC secret is empty")
+	}
+	return &keySigner{method: method, key: secret, kid: kid}, nil
+}
+
+// NewRSASigner returns a Signer using key with RS256.
+func NewRSASigner(key *rsa.PrivateKey, kid string) Signer {
+	return &keySigner{method: jwt.SigningMethodRS256, key: key, kid: kid}
+}
+
+// NewECDSASigner returns a Signer using key, which must be on the P-256 curve, with ES256.
+func NewECDSASigner(key *ecdsa.PrivateKey, kid string) (Signer, error) {
+	if key.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("ES256 requires a P-256 key, got %s", key.Curve.Params().Name)
+	}
+	return &keySigner{method: jwt.SigningMethodES256, key: key, kid: kid}, nil
+}
+
+// LoadPEMSigner reads a PEM-encoded RSA or P-256 ECDSA private key (PKCS#1, SEC 1 or PKCS#8)
+// from path and returns an RS256 or ES256 Signer for it.
+func LoadPEMSigner(path, kid string) (Signer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePEMSigner(b, kid)
+}
+
+// ParsePEMSigner is LoadPEMSigner for PEM data already in memory.
+func ParsePEMSigner(data []byte, kid string) (Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", block.Type, err)
+	}
+	return signerForKey(key, kid)
+}
+
+func signerForKey(key any, kid string) (Signer, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return NewRSASigner(k, kid), nil
+	case *ecdsa.PrivateKey:
+		return NewECDSASigner(k, kid)
+	}
+	return nil, fmt.Errorf("unsupported private key type %T", key)
+}
+
+// jwk is a JSON Web Key holding a private key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	D   string `json:"d"`
+	P   string `json:"p"`
+	Q   string `json:"q"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// LoadJWKSSigner reads a JWKS document from path and returns a Signer for the private key
+// with the given key ID, which is set as the kid header. kid may be empty when the document
+// holds a single key. Symmetric ("oct") keys use their alg member, defaulting to HS256; RSA
+// keys use RS256 and P-256 EC keys ES256.
+func LoadJWKSSigner(path, kid string) (Signer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKSSigner(b, kid)
+}
+
+// ParseJWKSSigner is LoadJWKSSigner for a JWKS document already in memory.
+func ParseJWKSSigner(data []byte, kid string) (Signer, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse JWKS: %w", err)
+	}
+	var key *jwk
+	for i := range set.Keys {
+		if kid == "" && len(set.Keys) == 1 || set.Keys[i].Kid == kid {
+			key = &set.Keys[i]
+			break
+		}
+	}
+	if key == nil {
+		if kid == "" {
+			return nil, fmt.Errorf("JWKS holds %d keys; a key ID is required", len(set.Keys))
+		}
+		return nil, fmt.Errorf("JWKS has no key %q", kid)
+	}
+	if key.D == "" && key.K == "" {
+		return nil, fmt.Errorf("JWKS key %q has no private key", key.Kid)
+	}
+
+	switch key.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(key.K)
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q: %w", key.Kid, err)
+		}
+		alg := key.Alg
+		if alg == "" {
+			alg = "HS256"
+		}
+		return NewHMACSigner(alg, secret, key.Kid)
+	case "RSA":
+		ints, err := jwkInts(key.N, key.E, key.D, key.P, key.Q)
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q: %w", key.Kid, err)
+		}
+		priv := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: ints[0], E: int(ints[1].Int64())},
+			D:         ints[2],
+			Primes:    []*big.Int{ints[3], ints[4]},
+		}
+		if err := priv.Validate(); err != nil {
+			return nil, fmt.Errorf("JWKS key %q: %w", key.Kid, err)
+		}
+		priv.Precompute()
+		return NewRSASigner(priv, key.Kid), nil
+	case "EC":
+		if key.Crv != "P-256" {
+			return nil, fmt.Errorf("JWKS key %q: unsupported curve %q", key.Kid, key.Crv)
+		}
+		ints, err := jwkInts(key.X, key.Y, key.D)
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q: %w", key.Kid, err)
+		}
+		priv := &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{Curve: elliptic.P256(), X: ints[0], Y: ints[1]},
+			D:         ints[2],
+		}
+		return NewECDSASigner(priv, key.Kid)
+	}
+	return nil, fmt.Errorf("JWKS key %q: unsupported key type %q", key.Kid, key.Kty)
+}
+
+// jwkInts decodes base64url-encoded big-endian integers.
+func jwkInts(values ...string) ([]*big.Int, error) {
+	ints := make([]*big.Int, len(values))
+	for i, v := range values {
+		if v == "" {
+			return nil, errors.New("missing key parameter")
+		}
+		b, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil {
+			return nil, err
+		}
+		ints[i] = new(big.Int).SetBytes(b)
+	}
+	return ints, nil
+}
+
+// TokenOptions sets the registered claims of tokens built by SignToken. Zero fields are
+// left out of the token.
+type TokenOptions struct {
+	ExpiresIn time.Duration // exp, relative to the time of signing
+	Issuer    string        // iss
+	Audience  []string      // aud
+	Subject   string        // sub
+	NotBefore time.Time     // nbf
+	ID        string        // jti
+
+	// UniqueID sets a random jti on every token when ID is empty.
+	UniqueID bool
+}
+
+// SignToken signs a token for user with signer, setting iat and the claims in opts.
+func SignToken(signer Signer, user UserClaims, opts TokenOptions) (string, error) {
+	claims, err := tokenClaims(user, opts, time.Now())
+	if err != nil {
+		return "", err
+	}
+	return signer.Sign(claims)
+}
+
+// tokenClaims returns the claims of a token for user signed at now.
+func tokenClaims(user UserClaims, opts TokenOptions, now time.Time) (Claims, error) {
+	claims := Claims{
+		User: user,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt: jwt.NewNumericDate(now),
+			Issuer:   opts.Issuer,
+			Audience: opts.Audience,
+			Subject:  opts.Subject,
+			ID:       opts.ID,
+		},
+	}
+	if opts.ExpiresIn != 0 {
+		claims.ExpiresAt = jwt.NewNumericDate(now.Add(opts.ExpiresIn))
+	}
+	if !opts.NotBefore.IsZero() {
+		claims.NotBefore = jwt.NewNumericDate(opts.NotBefore)
+	}
+	if claims.ID == "" && opts.UniqueID {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return Claims{}, err
+		}
+		claims.ID = hex.EncodeToString(b)
+	}
+	return claims, nil
+}
@@ -0,0 +1,197 @@
+package isbclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func parseSigned(t *testing.T, tokenStr string, key any) (*jwt.Token, *Claims) {
+	t.Helper()
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(*jwt.Token) (interface{}, error) { return key, nil })
+	if err != nil {
+		t.Fatalf("failed to parse token: %v", err)
+	}
+	return token, claims
+}
+
+func jwkInt(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+
+func TestHMACSigner(t *testing.T) {
+	for _, alg := range []string{"HS256", "HS384", "HS512"} {
+		signer, err := NewHMACSigner(alg, []byte("secret"), "k1")
+		if err != nil {
+			t.Fatalf("NewHMACSigner(%s) error: %v", alg, err)
+		}
+		tokenStr, err := SignToken(signer, NewAdminUserClaims("admin@example.com"), TokenOptions{ExpiresIn: time.Hour})
+		if err != nil {
+			t.Fatalf("SignToken error: %v", err)
+		}
+		token, _ := parseSigned(t, tokenStr, []byte("secret"))
+		if token.Method.Alg() != alg || token.Header["kid"] != "k1" {
+			t.Errorf("expected %s with kid k1, got %s %v", alg, token.Method.Alg(), token.Header["kid"])
+		}
+	}
+	if _, err := NewHMACSigner("HS1024", []byte("secret"), ""); err == nil {
+		t.Error("expected an error for an unsupported algorithm")
+	}
+	if _, err := NewHMACSigner("HS256", nil, ""); err == nil {
+		t.Error("expected an error for an empty secret")
+	}
+}
+
+func TestSignToken_Claims(t *testing.T) {
+	signer, _ := NewHMACSigner("HS256", []byte("secret"), "")
+	nbf := time.Now().Add(-time.Minute).Truncate(time.Second)
+	opts := TokenOptions{
+		ExpiresIn: time.Hour,
+		Issuer:    "isb-client",
+		Audience:  []string{"isb"},
+		Subject:   "admin@example.com",
+		NotBefore: nbf,
+		UniqueID:  true,
+	}
+	first, err := SignToken(signer, NewAdminUserClaims("admin@example.com"), opts)
+	if err != nil {
+		t.Fatalf("SignToken error: %v", err)
+	}
+	second, _ := SignToken(signer, NewAdminUserClaims("admin@example.com"), opts)
+
+	token, claims := parseSigned(t, first, []byte("secret"))
+	_, other := parseSigned(t, second, []byte("secret"))
+	if _, ok := token.Header["kid"]; ok {
+		t.Error("expected no kid header without a key ID")
+	}
+	if claims.Issuer != "isb-client" || claims.Subject != "admin@example.com" || len(claims.Audience) != 1 || claims.Audience[0] != "isb" {
+		t.Errorf("unexpected registered claims: %+v", claims.RegisteredClaims)
+	}
+	if !claims.NotBefore.Time.Equal(nbf) {
+		t.Errorf("expected nbf %v, got %v", nbf, claims.NotBefore)
+	}
+	if claims.ID == "" || claims.ID == other.ID {
+		t.Errorf("expected a unique jti per token, got %q and %q", claims.ID, other.ID)
+	}
+
+	opts.ID = "fixed"
+	fixed, _ := SignToken(signer, UserClaims{}, opts)
+	if _, claims := parseSigned(t, fixed, []byte("secret")); claims.ID != "fixed" {
+		t.Errorf("expected jti fixed, got %q", claims.ID)
+	}
+}
+
+func TestPEMSigner(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+
+	dir := t.TempDir()
+	tests := []struct {
+		name  string
+		block *pem.Block
+		alg   string
+		key   any
+	}{
+		{"pkcs1.pem", &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, "RS256", &rsaKey.PublicKey},
+		{"ec.pem", &pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}, "ES256", &ecKey.PublicKey},
+		{"pkcs8.pem", &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}, "ES256", &ecKey.PublicKey},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(tt.block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		signer, err := LoadPEMSigner(path, "pem-key")
+		if err != nil {
+			t.Fatalf("%s: LoadPEMSigner error: %v", tt.name, err)
+		}
+		if signer.Algorithm() != tt.alg || signer.KeyID() != "pem-key" {
+			t.Errorf("%s: expected %s/pem-key, got %s/%s", tt.name, tt.alg, signer.Algorithm(), signer.KeyID())
+		}
+		tokenStr, err := SignToken(signer, NewUserUserClaims("user@example.com"), TokenOptions{ExpiresIn: time.Hour})
+		if err != nil {
+			t.Fatalf("%s: SignToken error: %v", tt.name, err)
+		}
+		if token, claims := parseSigned(t, tokenStr, tt.key); token.Header["kid"] != "pem-key" || claims.User.Email != "user@example.com" {
+			t.Errorf("%s: unexpected token %v %+v", tt.name, token.Header, claims.User)
+		}
+	}
+
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	p384DER, _ := x509.MarshalECPrivateKey(p384)
+	if _, err := ParsePEMSigner(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: p384DER}), ""); err == nil {
+		t.Error("expected an error for a P-384 key")
+	}
+	if _, err := ParsePEMSigner([]byte("not pem"), ""); err == nil {
+		t.Error("expected an error for data without a PEM block")
+	}
+}
+
+func TestJWKSSigner(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, _ := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "oct", "kid": "hmac", "alg": "HS512", "k": base64.RawURLEncoding.EncodeToString([]byte("secret"))},
+		{"kty": "RSA", "kid": "rsa", "n": jwkInt(rsaKey.N), "e": jwkInt(big.NewInt(int64(rsaKey.E))), "d": jwkInt(rsaKey.D), "p": jwkInt(rsaKey.Primes[0]), "q": jwkInt(rsaKey.Primes[1])},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": jwkInt(ecKey.X), "y": jwkInt(ecKey.Y), "d": jwkInt(ecKey.D)},
+		{"kty": "EC", "kid": "public", "crv": "P-256", "x": jwkInt(ecKey.X), "y": jwkInt(ecKey.Y)},
+	}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, doc, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		kid, alg string
+		key      any
+	}{
+		{"hmac", "HS512", []byte("secret")},
+		{"rsa", "RS256", &rsaKey.PublicKey},
+		{"ec", "ES256", &ecKey.PublicKey},
+	}
+	for _, tt := range tests {
+		signer, err := LoadJWKSSigner(path, tt.kid)
+		if err != nil {
+			t.Fatalf("%s: LoadJWKSSigner error: %v", tt.kid, err)
+		}
+		tokenStr, err := SignToken(signer, NewAdminUserClaims("admin@example.com"), TokenOptions{ExpiresIn: time.Hour})
+		if err != nil {
+			t.Fatalf("%s: SignToken error: %v", tt.kid, err)
+		}
+		if token, _ := parseSigned(t, tokenStr, tt.key); token.Method.Alg() != tt.alg || token.Header["kid"] != tt.kid {
+			t.Errorf("%s: expected %s, got %s %v", tt.kid, tt.alg, token.Method.Alg(), token.Header["kid"])
+		}
+	}
+
+	for kid, want := range map[string]string{"public": "no private key", "missing": "no key", "": "key ID is required"} {
+		if _, err := ParseJWKSSigner(doc, kid); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseJWKSSigner(%q): expected an error containing %q, got %v", kid, want, err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package isbclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTemplateCacheTTL is how long lease templates fetched for name resolution are reused
+// when Client.TemplateCacheTTL is zero.
+const DefaultTemplateCacheTTL = 5 * time.Minute
+
+// maxTemplateSuggestions caps the close matches listed when a template name is not found.
+const maxTemplateSuggestions = 5
+
+// templateCache holds the lease templates fetched for name resolution. Concurrent lookups share
+// a single fetch.
+type templateCache struct {
+	mu        sync.Mutex
+	templates []LeaseTemplate
+	fetched   time.Time
+	flight    *templateFlight
+	// started counts the fetches started, numbering them.
+	started uint64
+	// generation is advanced by invalidate and by refreshes, so that a fetch started before a
+	// lease template was written, or before a refresh, is not cached.
+	generation uint64
+}
+
+// templateFlight is a fetch of the lease templates in progress.
+type templateFlight struct {
+	seq       uint64 // number of the fetch, from templateCache.started
+	refresh   bool   // the fetch bypasses any Cache installed on the client
+	done      chan struct{}
+	templates []LeaseTemplate
+	err       error
+	// abandoned is set when the fetch failed because the context of the lookup that started
+	// it was done; waiters with a live context fetch again.
+	abandoned bool
+}
+
+// invalidate drops the cached templates and detaches any fetch in progress.
+func (tc *templateCache) invalidate() {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.templates, tc.fetched, tc.flight = nil, time.Time{}, nil
+	tc.generation++
+}
+
+// ResolveLeaseTemplateUUID returns the UUID of the lease template named nameOrUUID. A UUID is
+// returned as is. Names match exactly first, then case-insensitively; a name matching several
+// templates fails with an *AmbiguousLeaseTemplateError, and an unknown name with a
+// *LeaseTemplateNotFoundError listing close matches.
+//
+// Templates are fetched with FetchAllLeaseTemplates and reused for Client.TemplateCacheTTL. A
+// name that is not in the cached templates is looked up again in freshly fetched ones, read
+// past any Cache installed on the client, so new templates resolve straight away.
+func (c *Client) ResolveLeaseTemplateUUID(ctx context.Context, nameOrUUID string) (string, error) {
+	if nameOrUUID == "" {
+		return "", &APIRequestError{Op: "param", URL: "", Err: fmt.Errorf("lease template name or UUID is required")}
+	}
+	if uuidFormat.MatchString(nameOrUUID) {
+		return nameOrUUID, nil
+	}
+	templates, seen, cached, err := c.leaseTemplates(ctx, false, 0)
+	if err != nil {
+		return "", err
+	}
+	tpl, err := matchLeaseTemplate(templates, nameOrUUID)
+	var notFound *LeaseTemplateNotFoundError
+	if cached && errors.As(err, &notFound) {
+		if templates, _, _, err = c.leaseTemplates(ctx, true, seen); err != nil {
+			return "", err
+		}
+		tpl, err = matchLeaseTemplate(templates, nameOrUUID)
+	}
+	if err != nil {
+		return "", err
+	}
+	return tpl.UUID, nil
+}
+
+// FilterLeasesByTemplate returns the leases in resp of the lease template named or identified by
+// nameOrUUID, resolved as by ResolveLeaseTemplateUUID. Unlike FilterByLeaseTemplateName it
+// matches leases by template UUID, so it is not affected by templates renamed since.
+func (c *Client) FilterLeasesByTemplate(ctx context.Context, resp *GetLeasesResponse, nameOrUUID string) ([]Lease, error) {
+	uuid, err := c.ResolveLeaseTemplateUUID(ctx, nameOrUUID)
+	if err != nil {
+		return nil, err
+	}
+	return resp.FilterByLeaseTemplateUUID(uuid), nil
+}
+
+// leaseTemplates returns the lease templates, from the cache unless they are older than the TTL,
+// and whether they came from the cache, along with the number of fetches started so far. A
+// lookup made while the templates are being fetched waits for that fetch.
+//
+// With refresh set the templates are fetched bypassing any Cache installed on the client, and
+// only a refreshing fetch started after the first since fetches is waited for, so that the
+// result is newer than a lookup that returned since.
+func (c *Client) leaseTemplates(ctx context.Context, refresh bool, since uint64) ([]LeaseTemplate, uint64, bool, error) {
+	ttl := durationOr(c.TemplateCacheTTL, DefaultTemplateCacheTTL)
+	tc := &c.templates
+	tc.mu.Lock()
+	seen := tc.started
+	if !refresh && ttl > 0 && !tc.fetched.IsZero() && time.Since(tc.fetched) < ttl {
+		templates := tc.templates
+		tc.mu.Unlock()
+		return templates, seen, true, nil
+	}
+	if f := tc.flight; f != nil && (!refresh || (f.refresh && f.seq > since)) {
+		tc.mu.Unlock()
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, seen, false, ctx.Err()
+		}
+		if f.abandoned && ctx.Err() == nil {
+			return c.leaseTemplates(ctx, refresh, since)
+		}
+		return f.templates, seen, false, f.err
+	}
+	tc.started++
+	f := &templateFlight{seq: tc.started, refresh: refresh, done: make(chan struct{})}
+	tc.flight = f
+	if refresh {
+		// An older fetch still in progress must not overwrite this one's result.
+		tc.generation++
+	}
+	generation := tc.generation
+	tc.mu.Unlock()
+
+	fetchCtx := ctx
+	if refresh {
+		fetchCtx = withoutCachedValue(ctx)
+	}
+	resp, err := c.FetchAllLeaseTemplates(fetchCtx, &GetLeaseTemplatesRequest{})
+	tc.mu.Lock()
+	if err == nil {
+		f.templates = resp.LeaseTemplates
+		if tc.generation == generation && ttl > 0 {
+			tc.templates, tc.fetched = resp.LeaseTemplates, time.Now()
+		}
+	}
+	f.err = err
+	f.abandoned = err != nil && ctx.Err() != nil
+	if tc.flight == f {
+		tc.flight = nil
+	}
+	tc.mu.Unlock()
+	close(f.done)
+	return f.templates, seen, false, err
+}
+
+// matchLeaseTemplate returns the template named name, matching exactly and then ignoring case.
+func matchLeaseTemplate(templates []LeaseTemplate, name string) (LeaseTemplate, error) {
+	for _, equal := range []func(a, b string) bool{
+		func(a, b string) bool { return a == b },
+		strings.EqualFold,
+	} {
+		var matches []LeaseTemplate
+		for _, tpl := range templates {
+			if equal(tpl.Name, name) {
+				matches = append(matches, tpl)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		default:
+			return LeaseTemplate{}, &AmbiguousLeaseTemplateError{Name: name, Matches: matches}
+		}
+	}
+	return LeaseTemplate{}, &LeaseTemplateNotFoundError{
+		APIResponseError: APIResponseError{Message: fmt.Sprintf("no lease template named %q", name)},
+		Suggestions:      closeTemplateNames(templates, name),
+	}
+}
+
+// closeTemplateNames returns the names of templates close to name: those containing it or
+// contained in it, or within a small edit distance, ignoring case. The closest come first.
+func closeTemplateNames(templates []LeaseTemplate, name string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+	lower := strings.ToLower(name)
+	limit := max(2, len([]rune(lower))/3)
+	var candidates []candidate
+	for _, tpl := range templates {
+		other := strings.ToLower(tpl.Name)
+		d := editDistance(lower, other)
+		if d <= limit || (other != "" && (strings.Contains(other, lower) || strings.Contains(lower, other))) {
+			candidates = append(candidates, candidate{tpl.Name, d})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	var names []string
+	for _, c := range candidates {
+		if len(names) == maxTemplateSuggestions {
+			break
+		}
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
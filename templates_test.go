@@ -0,0 +1,334 @@
+package isbclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	dataScienceUUID = "0b8a9c3e-1f2d-4e5a-9b6c-7d8e9f0a1b2c"
+	webUUID         = "1c9b0d4f-2a3e-4f6b-8c7d-8e9f0a1b2c3d"
+)
+
+// templateServer serves the given lease templates and counts how often they are listed. The
+// list can be changed through the returned pointer.
+func templateServer(t *testing.T, fetches *atomic.Int32, templates *[]LeaseTemplate) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/leaseTemplates":
+			fetches.Add(1)
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": map[string]any{"result": *templates, "nextPageIdentifier": nil}})
+		case "/leases":
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": Lease{UUID: "lease-1", UserEmail: "user@example.com", OriginalLeaseTemplateUuid: body["leaseTemplateUuid"]}})
+		case "/leaseTemplates/" + webUUID:
+			// Writes succeed; the test changes the listed templates itself.
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": LeaseTemplate{UUID: webUUID}})
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResolveLeaseTemplateUUID(t *testing.T) {
+	var fetches atomic.Int32
+	templates := []LeaseTemplate{
+		{UUID: dataScienceUUID, Name: "Data Science 7d"},
+		{UUID: webUUID, Name: "Web 1d"},
+		{UUID: "2d0c1e5a-3b4f-4a7c-9d8e-9f0a1b2c3d4e", Name: "data science 7D"},
+	}
+	client := NewClient(templateServer(t, &fetches, &templates).URL, "token")
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Data Science 7d", dataScienceUUID},
+		{"web 1D", webUUID},
+		{webUUID, webUUID},
+	}
+	for _, tt := range tests {
+		got, err := client.ResolveLeaseTemplateUUID(ctx, tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveLeaseTemplateUUID(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("expected the templates to be fetched once, got %d", fetches.Load())
+	}
+
+	var ambiguous *AmbiguousLeaseTemplateError
+	if _, err := client.ResolveLeaseTemplateUUID(ctx, "DATA SCIENCE 7D"); !errors.As(err, &ambiguous) || len(ambiguous.Matches) != 2 {
+		t.Errorf("expected an AmbiguousLeaseTemplateError, got %v", err)
+	}
+}
+
+func TestResolveLeaseTemplateUUID_NotFound(t *testing.T) {
+	var fetches atomic.Int32
+	templates := []LeaseTemplate{
+		{UUID: dataScienceUUID, Name: "Data Science 7d"},
+		{UUID: webUUID, Name: "Web 1d"},
+	}
+	client := NewClient(templateServer(t, &fetches, &templates).URL, "token")
+	ctx := context.Background()
+
+	_, err := client.ResolveLeaseTemplateUUID(ctx, "Data Sceince 7d")
+	var notFound *LeaseTemplateNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("expected a LeaseTemplateNotFoundError, got %v", err)
+	}
+	if len(notFound.Suggestions) != 1 || notFound.Suggestions[0] != "Data Science 7d" {
+		t.Errorf("expected Data Science 7d to be suggested, got %v", notFound.Suggestions)
+	}
+	if !strings.Contains(err.Error(), `did you mean "Data Science 7d"?`) || strings.Contains(err.Error(), "status") {
+		t.Errorf("unexpected message: %v", err)
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("expected one fetch for the first lookup, got %d", fetches.Load())
+	}
+
+	// A template created since the cache was filled is found by fetching again.
+	templates = append(templates, LeaseTemplate{UUID: "3e1d2f6b-4c5a-4b8d-8e9f-0a1b2c3d4e5f", Name: "GPU 3d"})
+	if uuid, err := client.ResolveLeaseTemplateUUID(ctx, "gpu 3d"); err != nil || uuid != templates[2].UUID {
+		t.Errorf("expected the new template, got %q %v", uuid, err)
+	}
+	if fetches.Load() != 2 {
+		t.Errorf("expected a refetch on a cache miss, got %d fetches", fetches.Load())
+	}
+
+	if _, err := client.ResolveLeaseTemplateUUID(ctx, ""); err == nil {
+		t.Error("expected an error for an empty name")
+	}
+}
+
+func TestResolveLeaseTemplateUUID_CacheDisabled(t *testing.T) {
+	var fetches atomic.Int32
+	templates := []LeaseTemplate{{UUID: webUUID, Name: "Web 1d"}}
+	client := NewClient(templateServer(t, &fetches, &templates).URL, "token")
+	client.TemplateCacheTTL = -1
+
+	for range 2 {
+		if _, err := client.ResolveLeaseTemplateUUID(context.Background(), "Web 1d"); err != nil {
+			t.Fatalf("ResolveLeaseTemplateUUID error: %v", err)
+		}
+	}
+	if fetches.Load() != 2 {
+		t.Errorf("expected a fetch per lookup, got %d", fetches.Load())
+	}
+}
+
+func TestResolveLeaseTemplateUUID_RefreshBypassesCache(t *testing.T) {
+	var fetches atomic.Int32
+	templates := []LeaseTemplate{{UUID: webUUID, Name: "Web 1d"}}
+	client := NewClient(templateServer(t, &fetches, &templates).URL, "token")
+	client.Use(NewCache(CacheOptions{}).Middleware())
+	ctx := context.Background()
+
+	if _, err := client.ResolveLeaseTemplateUUID(ctx, "Web 1d"); err != nil {
+		t.Fatalf("ResolveLeaseTemplateUUID error: %v", err)
+	}
+	templates = append(templates, LeaseTemplate{UUID: dataScienceUUID, Name: "Data Science 7d"})
+	if uuid, err := client.ResolveLeaseTemplateUUID(ctx, "Data Science 7d"); err != nil || uuid != dataScienceUUID {
+		t.Errorf("expected a new template to resolve past the Cache, got %q %v", uuid, err)
+	}
+	if fetches.Load() != 2 {
+		t.Errorf("expected a fetch past the Cache on a miss, got %d fetches", fetches.Load())
+	}
+}
+
+func TestLeaseTemplates_RefreshDoesNotJoinOlderFetch(t *testing.T) {
+	var fetches atomic.Int32
+	releaseOld := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := "Web 1d"
+		if fetches.Add(1) == 1 {
+			<-releaseOld
+		} else {
+			name = "Web 2d"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": map[string]any{"result": []LeaseTemplate{{UUID: webUUID, Name: name}}, "nextPageIdentifier": nil}})
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	ctx := context.Background()
+
+	older := make(chan []LeaseTemplate, 1)
+	go func() {
+		templates, _, _, _ := client.leaseTemplates(ctx, false, 0)
+		older <- templates
+	}()
+	for fetches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A refresh after the first fetch started does not wait for it.
+	templates, _, _, err := client.leaseTemplates(ctx, true, 1)
+	if err != nil || len(templates) != 1 || templates[0].Name != "Web 2d" {
+		t.Errorf("expected the refreshed templates, got %v %v", templates, err)
+	}
+	close(releaseOld)
+	if templates := <-older; templates[0].Name != "Web 1d" {
+		t.Errorf("expected the first lookup to get its own fetch, got %v", templates)
+	}
+	if templates, _, cached, _ := client.leaseTemplates(ctx, false, 0); !cached || templates[0].Name != "Web 2d" {
+		t.Errorf("expected the older fetch not to replace the refreshed templates, got %v cached %t", templates, cached)
+	}
+}
+
+func TestResolveLeaseTemplateUUID_InvalidatedByWrites(t *testing.T) {
+	var fetches atomic.Int32
+	templates := []LeaseTemplate{{UUID: webUUID, Name: "Web 1d"}}
+	client := NewClient(templateServer(t, &fetches, &templates).URL, "token")
+	ctx := context.Background()
+
+	if _, err := client.ResolveLeaseTemplateUUID(ctx, "Web 1d"); err != nil {
+		t.Fatalf("ResolveLeaseTemplateUUID error: %v", err)
+	}
+
+	var notFound *LeaseTemplateNotFoundError
+	templates = []LeaseTemplate{{UUID: webUUID, Name: "Web 2d"}}
+	if _, err := client.UpdateLeaseTemplate(ctx, &UpdateLeaseTemplateRequest{LeaseTemplateID: webUUID, Name: "Web 2d"}); err != nil {
+		t.Fatalf("UpdateLeaseTemplate error: %v", err)
+	}
+	if _, err := client.ResolveLeaseTemplateUUID(ctx, "Web 1d"); !errors.As(err, &notFound) {
+		t.Errorf("expected the old name not to resolve after a rename, got %v", err)
+	}
+
+	templates = nil
+	if err := client.DeleteLeaseTemplate(ctx, &DeleteLeaseTemplateRequest{LeaseTemplateID: webUUID}); err != nil {
+		t.Fatalf("DeleteLeaseTemplate error: %v", err)
+	}
+	if _, err := client.ResolveLeaseTemplateUUID(ctx, "Web 2d"); !errors.As(err, &notFound) {
+		t.Errorf("expected a deleted template not to resolve, got %v", err)
+	}
+	if fetches.Load() != 3 {
+		t.Errorf("expected a fetch after each write, got %d fetches", fetches.Load())
+	}
+}
+
+func TestResolveLeaseTemplateUUID_CoalescesFetches(t *testing.T) {
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": map[string]any{"result": []LeaseTemplate{{UUID: webUUID, Name: "Web 1d"}}, "nextPageIdentifier": nil}})
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	// The first lookup is cancelled while the others wait for its fetch; they fetch again.
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := client.ResolveLeaseTemplateUUID(ctx, "Web 1d")
+		first <- err
+	}()
+	for fetches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			uuid, err := client.ResolveLeaseTemplateUUID(context.Background(), "Web 1d")
+			if err == nil && uuid != webUUID {
+				err = fmt.Errorf("resolved to %q", uuid)
+			}
+			errs <- err
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancelled lookup to fail with context.Canceled, got %v", err)
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("ResolveLeaseTemplateUUID error: %v", err)
+		}
+	}
+	if fetches.Load() != 2 {
+		t.Errorf("expected concurrent lookups to share a fetch, got %d fetches", fetches.Load())
+	}
+}
+
+func TestCreateLease_ByTemplateName(t *testing.T) {
+	var fetches atomic.Int32
+	templates := []LeaseTemplate{{UUID: dataScienceUUID, Name: "Data Science 7d"}}
+	client := NewClient(templateServer(t, &fetches, &templates).URL, "token")
+
+	req := &CreateLeaseRequest{LeaseTemplateName: "data science 7d"}
+	resp, err := client.CreateLease(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateLease error: %v", err)
+	}
+	if resp.Lease.OriginalLeaseTemplateUuid != dataScienceUUID {
+		t.Errorf("expected the resolved UUID to be sent, got %q", resp.Lease.OriginalLeaseTemplateUuid)
+	}
+	if req.LeaseTemplateUUID != "" {
+		t.Error("expected the request not to be modified")
+	}
+
+	var notFound *LeaseTemplateNotFoundError
+	if _, err := client.CreateLease(context.Background(), &CreateLeaseRequest{LeaseTemplateName: "Unknown"}); !errors.As(err, &notFound) {
+		t.Errorf("expected a LeaseTemplateNotFoundError, got %v", err)
+	}
+	if _, err := client.CreateLease(context.Background(), &CreateLeaseRequest{}); err == nil {
+		t.Error("expected an error without a template")
+	}
+}
+
+func TestFilterLeasesByTemplate(t *testing.T) {
+	var fetches atomic.Int32
+	templates := []LeaseTemplate{{UUID: dataScienceUUID, Name: "Data Science 7d"}}
+	client := NewClient(templateServer(t, &fetches, &templates).URL, "token")
+	resp := &GetLeasesResponse{Leases: []Lease{
+		{UUID: "a", OriginalLeaseTemplateUuid: dataScienceUUID, OriginalLeaseTemplateName: "Data Science (old name)"},
+		{UUID: "b", OriginalLeaseTemplateUuid: webUUID},
+	}}
+
+	leases, err := client.FilterLeasesByTemplate(context.Background(), resp, "Data Science 7d")
+	if err != nil || len(leases) != 1 || leases[0].UUID != "a" {
+		t.Errorf("expected lease a, got %v %v", leases, err)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"web", "", 3},
+		{"kitten", "sitting", 3},
+		{"science", "sceince", 2},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
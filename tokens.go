@@ -0,0 +1,167 @@
+package isbclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TokenSource supplies the bearer tokens requests are authenticated with.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenRefresher is a TokenSource that can replace a token the API rejected.
+type TokenRefresher interface {
+	TokenSource
+
+	// Refresh is called with a token a request was rejected with and reports whether
+	// Token now returns a different token worth retrying with.
+	Refresh(ctx context.Context, rejected string) (bool, error)
+}
+
+// tokenAcceptor is implemented by token sources that want to know which refreshed token the
+// API accepted.
+type tokenAcceptor interface {
+	accepted(token string)
+}
+
+// Authenticate returns middleware that authenticates every request with a token from src in
+// place of Client.Token. When src is a TokenRefresher and a request is rejected with a 401
+// *UnauthorizedError, the token is refreshed and the request is sent once more. A 403 denies
+// the permission rather than the token, so the token is kept.
+//
+// CreateLeaseAsUser is authenticated with the token it mints and is left alone.
+func Authenticate(src TokenSource) Middleware {
+	refresher, _ := src.(TokenRefresher)
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			if call.Method == "" || call.Operation == "CreateLeaseAsUser" {
+				return next(ctx, call)
+			}
+			token, err := src.Token(ctx)
+			if err != nil {
+				return nil, &APIRequestError{Op: "token", URL: call.Path, Err: err}
+			}
+			call.token = token
+			res, err := next(ctx, call)
+			var unauthorized *UnauthorizedError
+			if refresher == nil || !errors.As(err, &unauthorized) || unauthorized.StatusCode != http.StatusUnauthorized || ctx.Err() != nil {
+				return res, err
+			}
+			if ok, rerr := refresher.Refresh(ctx, token); rerr != nil || !ok {
+				return res, err
+			}
+			if token, err = src.Token(ctx); err != nil {
+				return nil, &APIRequestError{Op: "token", URL: call.Path, Err: err}
+			}
+			call.token, call.Response = token, nil
+			res, err = next(ctx, call)
+			if acceptor, ok := src.(tokenAcceptor); ok && err == nil {
+				acceptor.accepted(token)
+			}
+			return res, err
+		}
+	}
+}
+
+// RotationOptions configures a SecretRotation.
+type RotationOptions struct {
+	// User is the identity tokens are signed for.
+	User UserClaims
+	// ExpiresIn is the lifetime of signed tokens (default 15 minutes). Tokens are reused
+	// until half of it has passed.
+	ExpiresIn time.Duration
+	// OnAccepted is called with the index of a secret the API accepted after the secret in
+	// use was rejected, for example to alert that a rotation has completed.
+	OnAccepted func(index int)
+}
+
+// SecretRotation is a TokenRefresher signing HS256 tokens with one of an ordered list of
+// secrets, newest first, so that a rotated JwtSecretArn secret can be rolled out without
+// downtime: list both the new and the old secret and requests rejected with one are retried
+// with the next.
+//
+// Tokens are signed with the newest secret until it is rejected; the rotation then moves on
+// to the next secret, wrapping around after the last, and stays with whichever the API
+// accepts.
+type SecretRotation struct {
+	signers    []Signer
+	opts       RotationOptions
+	mu         sync.Mutex
+	active     int
+	token      string
+	refreshAt  time.Time
+	candidates map[string]int // tokens handed out, by secret index
+}
+
+// NewSecretRotation returns a SecretRotation over secrets, ordered newest first.
+func NewSecretRotation(secrets []string, opts RotationOptions) (*SecretRotation, error) {
+	if len(secrets) == 0 {
+		return nil, errors.New("at least one secret is required")
+	}
+	if opts.ExpiresIn <= 0 {
+		opts.ExpiresIn = 15 * time.Minute
+	}
+	r := &SecretRotation{opts: opts, candidates: map[string]int{}}
+	for i, secret := range secrets {
+		signer, err := NewHMACSigner("HS256", []byte(secret), "")
+		if err != nil {
+			return nil, fmt.Errorf("secret %d: %w", i, err)
+		}
+		r.signers = append(r.signers, signer)
+	}
+	return r, nil
+}
+
+// Token returns a token signed with the secret in use.
+func (r *SecretRotation) Token(context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.token != "" && time.Now().Before(r.refreshAt) {
+		return r.token, nil
+	}
+	token, err := SignToken(r.signers[r.active], r.opts.User, TokenOptions{ExpiresIn: r.opts.ExpiresIn})
+	if err != nil {
+		return "", err
+	}
+	r.token, r.refreshAt = token, time.Now().Add(r.opts.ExpiresIn/2)
+	r.candidates = map[string]int{token: r.active}
+	return token, nil
+}
+
+// Refresh moves on to the next secret if rejected was signed with the secret in use. It
+// reports false when there is no other secret to try.
+func (r *SecretRotation) Refresh(_ context.Context, rejected string) (bool, error) {
+	if len(r.signers) == 1 {
+		return false, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// A concurrent request may have moved on already; retry with the current secret then.
+	if rejected == r.token {
+		r.active = (r.active + 1) % len(r.signers)
+		r.token = ""
+	}
+	return true, nil
+}
+
+// Active returns the index of the secret tokens are signed with.
+func (r *SecretRotation) Active() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.active
+}
+
+func (r *SecretRotation) accepted(token string) {
+	r.mu.Lock()
+	index, ok := r.candidates[token]
+	delete(r.candidates, token)
+	r.mu.Unlock()
+	if ok && r.opts.OnAccepted != nil {
+		r.opts.OnAccepted(index)
+	}
+}
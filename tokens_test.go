@@ -0,0 +1,147 @@
+package isbclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// secretServer accepts tokens signed with secret and answers others with 401.
+func secretServer(t *testing.T, secret *atomic.Value, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if _, err := VerifyJWT(token, VerifyOptions{Secret: secret.Load().(string)}); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"status":"fail","data":{"errors":[{"message":"invalid token"}]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"uuid":"lease-1"}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSecretRotation(t *testing.T) {
+	var secret atomic.Value
+	secret.Store("old")
+	var requests atomic.Int32
+	server := secretServer(t, &secret, &requests)
+
+	var accepted []int
+	rotation, err := NewSecretRotation([]string{"new", "old"}, RotationOptions{
+		User:       NewAdminUserClaims("admin@example.com"),
+		OnAccepted: func(index int) { accepted = append(accepted, index) },
+	})
+	if err != nil {
+		t.Fatalf("NewSecretRotation error: %v", err)
+	}
+	client := NewClient(server.URL, "")
+	client.Use(Authenticate(rotation))
+
+	// The new secret is not live yet: the request is retried with the old one.
+	if _, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"}); err != nil {
+		t.Fatalf("GetLeaseByID error: %v", err)
+	}
+	if requests.Load() != 2 || rotation.Active() != 1 || len(accepted) != 1 || accepted[0] != 1 {
+		t.Errorf("expected a retry with secret 1, got %d requests, active %d, accepted %v", requests.Load(), rotation.Active(), accepted)
+	}
+
+	// Later requests keep using the accepted secret.
+	requests.Store(0)
+	if _, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"}); err != nil {
+		t.Fatalf("GetLeaseByID error: %v", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected a single request, got %d", requests.Load())
+	}
+
+	// The rotation completes: the old secret is rejected and the rotation wraps to the new one.
+	secret.Store("new")
+	if _, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"}); err != nil {
+		t.Fatalf("GetLeaseByID error: %v", err)
+	}
+	if rotation.Active() != 0 || len(accepted) != 2 || accepted[1] != 0 {
+		t.Errorf("expected secret 0 to be accepted, got active %d, accepted %v", rotation.Active(), accepted)
+	}
+}
+
+func TestSecretRotation_RetriesOnce(t *testing.T) {
+	var secret atomic.Value
+	secret.Store("unknown")
+	var requests atomic.Int32
+	server := secretServer(t, &secret, &requests)
+
+	rotation, _ := NewSecretRotation([]string{"a", "b", "c"}, RotationOptions{User: NewAdminUserClaims("admin@example.com")})
+	client := NewClient(server.URL, "")
+	client.Use(Authenticate(rotation))
+
+	_, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"})
+	var unauthorized *UnauthorizedError
+	if !errors.As(err, &unauthorized) {
+		t.Fatalf("expected UnauthorizedError, got %T %v", err, err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("expected one retry, got %d requests", requests.Load())
+	}
+}
+
+func TestSecretRotation_KeepsSecretOnForbidden(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"status":"fail","data":{"errors":[{"message":"forbidden"}]}}`))
+	}))
+	defer server.Close()
+
+	var accepted []int
+	rotation, _ := NewSecretRotation([]string{"new", "old"}, RotationOptions{
+		User:       NewAdminUserClaims("admin@example.com"),
+		OnAccepted: func(index int) { accepted = append(accepted, index) },
+	})
+	client := NewClient(server.URL, "")
+	client.Use(Authenticate(rotation))
+	before, _ := rotation.Token(context.Background())
+
+	_, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"})
+	var unauthorized *UnauthorizedError
+	if !errors.As(err, &unauthorized) || unauthorized.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected a 403 UnauthorizedError, got %T %v", err, err)
+	}
+	after, _ := rotation.Token(context.Background())
+	if requests.Load() != 1 || rotation.Active() != 0 || after != before || len(accepted) != 0 {
+		t.Errorf("expected a 403 not to refresh, got %d requests, active %d, accepted %v, token changed %t", requests.Load(), rotation.Active(), accepted, after != before)
+	}
+}
+
+func TestAuthenticate_TokenError(t *testing.T) {
+	client := NewClient("http://127.0.0.1:0", "")
+	client.Use(Authenticate(tokenFunc(func(context.Context) (string, error) { return "", errors.New("no token") })))
+
+	_, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"})
+	var reqErr *APIRequestError
+	if !errors.As(err, &reqErr) || reqErr.Op != "token" {
+		t.Errorf("expected a token error, got %T %v", err, err)
+	}
+}
+
+type tokenFunc func(context.Context) (string, error)
+
+func (f tokenFunc) Token(ctx context.Context) (string, error) { return f(ctx) }
+
+func TestNewSecretRotation_Errors(t *testing.T) {
+	if _, err := NewSecretRotation(nil, RotationOptions{}); err == nil {
+		t.Error("expected an error without secrets")
+	}
+	if _, err := NewSecretRotation([]string{"a", ""}, RotationOptions{}); err == nil {
+		t.Error("expected an error for an empty secret")
+	}
+}
@@ -1,19 +1,32 @@
 package isbclient
 
 import (
+	"bytes"
 	"encoding/json"
+	"maps"
 	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 )
 
 const (
 	ReviewApprove = "Approve"
 	ReviewDeny    = "Deny"
 
-	StatusActive             = "Active"
-	StatusDenied             = "ApprovalDenied"
+	// Deprecated: Use LeaseStatusActive.
+	StatusActive = "Active"
+	// Deprecated: ApprovalDenied is not a lease status in the API spec; a denied lease is
+	// LeaseStatusTerminated.
+	StatusDenied = "ApprovalDenied"
+	// Deprecated: ManuallyTerminated is not a lease status in the API spec; use
+	// LeaseStatusTerminated.
 	StatusManuallyTerminated = "ManuallyTerminated"
-	StatusFrozen             = "Frozen"
-	StatusExpired            = "Expired"
+	// Deprecated: Use LeaseStatusFrozen.
+	StatusFrozen = "Frozen"
+	// Deprecated: Use LeaseStatusExpired.
+	StatusExpired = "Expired"
 )
 
 // FailResponseBody represents a failed API response.
@@ -67,6 +80,8 @@ type Lease struct {
 	EndDate                   string              `json:"endDate"`
 	TotalCostAccrued          float64             `json:"totalCostAccrued"`
 	Meta                      MetaData            `json:"meta"`
+
+	unknown map[string]json.RawMessage
 }
 
 // LeaseTemplate represents a lease template (fully defined)
@@ -81,6 +96,8 @@ type LeaseTemplate struct {
 	LeaseDurationInHours int                 `json:"leaseDurationInHours"`
 	DurationThresholds   []DurationThreshold `json:"durationThresholds"`
 	Meta                 MetaData            `json:"meta"`
+
+	unknown map[string]json.RawMessage
 }
 
 // Account represents an account (fully defined)
@@ -89,6 +106,8 @@ type Account struct {
 	Status          string   `json:"status"`
 	DriftAtLastScan bool     `json:"driftAtLastScan"`
 	Meta            MetaData `json:"meta"`
+
+	unknown map[string]json.RawMessage
 }
 
 // UnregisteredAccount represents an unregistered account
@@ -108,8 +127,10 @@ type GlobalConfiguration struct {
 	MaintenanceMode bool                     `json:"maintenanceMode"`
 	Leases          GlobalLeasesConfig       `json:"leases"`
 	Cleanup         GlobalCleanupConfig      `json:"cleanup"`
-	Auth            map[string]interface{}   `json:"auth"`
+	Auth            GlobalAuthConfig         `json:"auth"`
 	Notification    GlobalNotificationConfig `json:"notification"`
+
+	unknown map[string]json.RawMessage
 }
 
 type GlobalLeasesConfig struct {
@@ -132,6 +153,19 @@ type GlobalNotificationConfig struct {
 	EmailFrom string `json:"emailFrom"`
 }
 
+// GlobalAuthConfig is the auth section of the global configuration. The spec leaves it
+// undefined, so members not modelled here are retained like those of GlobalConfiguration.
+type GlobalAuthConfig struct {
+	IdpSignInUrl             string `json:"idpSignInUrl,omitempty"`
+	IdpSignOutUrl            string `json:"idpSignOutUrl,omitempty"`
+	IdpAudience              string `json:"idpAudience,omitempty"`
+	WebAppUrl                string `json:"webAppUrl,omitempty"`
+	AwsAccessPortalUrl       string `json:"awsAccessPortalUrl,omitempty"`
+	SessionDurationInMinutes int    `json:"sessionDurationInMinutes,omitempty"`
+
+	unknown map[string]json.RawMessage
+}
+
 // PageRequestBase provides a base for paginated requests.
 type PageRequestBase struct {
 	PageIdentifier string
@@ -195,6 +229,9 @@ func (r *GetLeaseByIDRequest) BuildQuery() url.Values {
 
 type CreateLeaseRequest struct {
 	LeaseTemplateUUID string
+	// LeaseTemplateName identifies the template by name when LeaseTemplateUUID is empty; see
+	// Client.ResolveLeaseTemplateUUID.
+	LeaseTemplateName string
 	Comments          string
 }
 
@@ -258,35 +295,34 @@ func (r *GetAccountsRequest) BuildQuery() url.Values {
 	return q
 }
 
-// Paginated and result wrapper structs
-
-type PaginatedResults[T any] struct {
-	Items              []T    `json:"items"`
-	NextPageIdentifier string `json:"nextPageIdentifier"`
-}
-
-type PaginatedLeases struct {
-	Items              []Lease `json:"items"`
-	NextPageIdentifier string  `json:"nextPageIdentifier"`
-	Result             []Lease `json:"result"`
+type GetUnregisteredAccountsRequest struct {
+	PageIdentifier string
+	PageSize       string
 }
 
-type PaginatedLeaseTemplates struct {
-	Items              []LeaseTemplate `json:"items"`
-	NextPageIdentifier string          `json:"nextPageIdentifier"`
-	Result             []LeaseTemplate `json:"result"`
+func (r *GetUnregisteredAccountsRequest) SetPageIdentifier(next string) {
+	r.PageIdentifier = next
 }
 
-type PaginatedAccounts struct {
-	Items              []Account `json:"items"`
-	NextPageIdentifier string    `json:"nextPageIdentifier"`
-	Result             []Account `json:"result"`
+func (r *GetUnregisteredAccountsRequest) BuildQuery() url.Values {
+	if r == nil {
+		return url.Values{}
+	}
+	q := url.Values{}
+	if r.PageIdentifier != "" {
+		q.Set("pageIdentifier", r.PageIdentifier)
+	}
+	if r.PageSize != "" {
+		q.Set("pageSize", r.PageSize)
+	}
+	return q
 }
 
-type PaginatedUnregisteredAccounts struct {
-	Items              []UnregisteredAccount `json:"items"`
-	NextPageIdentifier string                `json:"nextPageIdentifier"`
-	Result             []UnregisteredAccount `json:"result"`
+// Page is the data member of every paginated list response: one page of results and the
+// identifier of the next page, empty on the last page.
+type Page[T any] struct {
+	Result             []T    `json:"result"`
+	NextPageIdentifier string `json:"nextPageIdentifier,omitempty"`
 }
 
 // Response wrapper structs for client methods
@@ -319,17 +355,17 @@ func (r *GetLeasesResponse) FilterByLeaseTemplateUUID(uuid string) []Lease {
 }
 
 type GetLeaseTemplatesResponse struct {
-	LeaseTemplates     []LeaseTemplate `json:"leaseTemplates"`
+	LeaseTemplates     []LeaseTemplate `json:"result"`
 	NextPageIdentifier string          `json:"nextPageIdentifier,omitempty"`
 }
 
 type GetAccountsResponse struct {
-	Accounts           []Account `json:"accounts"`
+	Accounts           []Account `json:"result"`
 	NextPageIdentifier string    `json:"nextPageIdentifier,omitempty"`
 }
 
 type GetUnregisteredAccountsResponse struct {
-	UnregisteredAccounts []UnregisteredAccount `json:"unregisteredAccounts"`
+	UnregisteredAccounts []UnregisteredAccount `json:"result"`
 	NextPageIdentifier   string                `json:"nextPageIdentifier,omitempty"`
 }
 
@@ -354,6 +390,13 @@ type UpdateLeaseTemplateResponse struct {
 	LeaseTemplate LeaseTemplate `json:"data"`
 }
 
+// GetLeaseTemplateByIDResponse represents the response for fetching a lease template.
+// GET /leaseTemplates/{leaseTemplateId}
+// Contains a single LeaseTemplate.
+type GetLeaseTemplateByIDResponse struct {
+	LeaseTemplate LeaseTemplate `json:"data"`
+}
+
 // RegisterAccountResponse represents the response for registering an account.
 // POST /accounts
 // Contains a single Account.
@@ -364,7 +407,7 @@ type RegisterAccountResponse struct {
 // UpdateLeaseRequest represents a request to update a lease.
 // PATCH /leases/{leaseId}
 type UpdateLeaseRequest struct {
-	LeaseID            string
+	LeaseID            string               `json:"-"`
 	MaxSpend           *float64             `json:"maxSpend,omitempty"`
 	BudgetThresholds   *[]BudgetThreshold   `json:"budgetThresholds,omitempty"`
 	ExpirationDate     *string              `json:"expirationDate,omitempty"`
@@ -393,7 +436,7 @@ type TerminateLeaseRequest struct {
 // UpdateLeaseTemplateRequest represents a request to update a lease template.
 // PUT /leaseTemplates/{leaseTemplateId}
 type UpdateLeaseTemplateRequest struct {
-	LeaseTemplateID      string
+	LeaseTemplateID      string              `json:"-"`
 	Name                 string              `json:"name"`
 	Description          string              `json:"description"`
 	RequiresApproval     bool                `json:"requiresApproval"`
@@ -402,6 +445,38 @@ type UpdateLeaseTemplateRequest struct {
 	BudgetThresholds     []BudgetThreshold   `json:"budgetThresholds"`
 	DurationThresholds   []DurationThreshold `json:"durationThresholds"`
 	CreatedBy            string              `json:"createdBy"`
+
+	unknown map[string]json.RawMessage
+}
+
+// NewUpdateLeaseTemplateRequest builds a full-replacement update from a fetched
+// LeaseTemplate, carrying over any fields this client does not model so that a
+// fetch-modify-put cycle does not drop them.
+func NewUpdateLeaseTemplateRequest(tpl LeaseTemplate) *UpdateLeaseTemplateRequest {
+	return &UpdateLeaseTemplateRequest{
+		LeaseTemplateID:      tpl.UUID,
+		Name:                 tpl.Name,
+		Description:          tpl.Description,
+		RequiresApproval:     tpl.RequiresApproval,
+		MaxSpend:             tpl.MaxSpend,
+		LeaseDurationInHours: tpl.LeaseDurationInHours,
+		BudgetThresholds:     tpl.BudgetThresholds,
+		DurationThresholds:   tpl.DurationThresholds,
+		CreatedBy:            tpl.CreatedBy,
+		unknown:              maps.Clone(tpl.unknown),
+	}
+}
+
+// MarshalJSON encodes the request body, including any carried-over unknown fields.
+func (r UpdateLeaseTemplateRequest) MarshalJSON() ([]byte, error) {
+	type plain UpdateLeaseTemplateRequest
+	return marshalWithUnknown(plain(r), r.unknown)
+}
+
+// GetLeaseTemplateByIDRequest represents a request to fetch a single lease template (no body).
+// GET /leaseTemplates/{leaseTemplateId}
+type GetLeaseTemplateByIDRequest struct {
+	LeaseTemplateID string
 }
 
 // DeleteLeaseTemplateRequest represents a request to delete a lease template (no body).
@@ -433,3 +508,198 @@ type EjectAccountRequest struct {
 type GetLeaseByIDResponse struct {
 	Lease Lease `json:"lease"`
 }
+
+// Unknown field preservation
+//
+// Lease, LeaseTemplate, Account, GlobalConfiguration and GlobalAuthConfig keep any JSON members
+// they do not model, and write them back when encoded. This keeps
+// read-modify-write cycles lossless against newer servers.
+
+// UnknownFields returns a copy of the JSON members not modelled by Lease.
+func (l Lease) UnknownFields() map[string]json.RawMessage {
+	return maps.Clone(l.unknown)
+}
+
+// UnmarshalJSON decodes a Lease and retains unknown members.
+func (l *Lease) UnmarshalJSON(data []byte) error {
+	type plain Lease
+	unknown, err := unmarshalWithUnknown(data, (*plain)(l))
+	if err != nil {
+		return err
+	}
+	l.unknown = unknown
+	return nil
+}
+
+// MarshalJSON encodes a Lease including any retained unknown members.
+func (l Lease) MarshalJSON() ([]byte, error) {
+	type plain Lease
+	return marshalWithUnknown(plain(l), l.unknown)
+}
+
+// UnknownFields returns a copy of the JSON members not modelled by LeaseTemplate.
+func (t LeaseTemplate) UnknownFields() map[string]json.RawMessage {
+	return maps.Clone(t.unknown)
+}
+
+// UnmarshalJSON decodes a LeaseTemplate and retains unknown members.
+func (t *LeaseTemplate) UnmarshalJSON(data []byte) error {
+	type plain LeaseTemplate
+	unknown, err := unmarshalWithUnknown(data, (*plain)(t))
+	if err != nil {
+		return err
+	}
+	t.unknown = unknown
+	return nil
+}
+
+// MarshalJSON encodes a LeaseTemplate including any retained unknown members.
+func (t LeaseTemplate) MarshalJSON() ([]byte, error) {
+	type plain LeaseTemplate
+	return marshalWithUnknown(plain(t), t.unknown)
+}
+
+// UnknownFields returns a copy of the JSON members not modelled by Account.
+func (a Account) UnknownFields() map[string]json.RawMessage {
+	return maps.Clone(a.unknown)
+}
+
+// UnmarshalJSON decodes an Account and retains unknown members.
+func (a *Account) UnmarshalJSON(data []byte) error {
+	type plain Account
+	unknown, err := unmarshalWithUnknown(data, (*plain)(a))
+	if err != nil {
+		return err
+	}
+	a.unknown = unknown
+	return nil
+}
+
+// MarshalJSON encodes an Account including any retained unknown members.
+func (a Account) MarshalJSON() ([]byte, error) {
+	type plain Account
+	return marshalWithUnknown(plain(a), a.unknown)
+}
+
+// UnknownFields returns a copy of the JSON members not modelled by GlobalConfiguration.
+func (g GlobalConfiguration) UnknownFields() map[string]json.RawMessage {
+	return maps.Clone(g.unknown)
+}
+
+// UnmarshalJSON decodes a GlobalConfiguration and retains unknown members.
+func (g *GlobalConfiguration) UnmarshalJSON(data []byte) error {
+	type plain GlobalConfiguration
+	unknown, err := unmarshalWithUnknown(data, (*plain)(g))
+	if err != nil {
+		return err
+	}
+	g.unknown = unknown
+	return nil
+}
+
+// MarshalJSON encodes a GlobalConfiguration including any retained unknown members.
+func (g GlobalConfiguration) MarshalJSON() ([]byte, error) {
+	type plain GlobalConfiguration
+	return marshalWithUnknown(plain(g), g.unknown)
+}
+
+// UnknownFields returns a copy of the JSON members not modelled by GlobalAuthConfig.
+func (a GlobalAuthConfig) UnknownFields() map[string]json.RawMessage {
+	return maps.Clone(a.unknown)
+}
+
+// UnmarshalJSON decodes a GlobalAuthConfig and retains unknown members.
+func (a *GlobalAuthConfig) UnmarshalJSON(data []byte) error {
+	type plain GlobalAuthConfig
+	unknown, err := unmarshalWithUnknown(data, (*plain)(a))
+	if err != nil {
+		return err
+	}
+	a.unknown = unknown
+	return nil
+}
+
+// MarshalJSON encodes a GlobalAuthConfig including any retained unknown members.
+func (a GlobalAuthConfig) MarshalJSON() ([]byte, error) {
+	type plain GlobalAuthConfig
+	return marshalWithUnknown(plain(a), a.unknown)
+}
+
+// knownFieldNames caches the JSON member names modelled by each struct type.
+var knownFieldNames sync.Map // map[reflect.Type][]string
+
+// jsonFieldNames returns the JSON member names encoding/json maps onto the fields of t.
+func jsonFieldNames(t reflect.Type) []string {
+	if names, ok := knownFieldNames.Load(t); ok {
+		return names.([]string)
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	knownFieldNames.Store(t, names)
+	return names
+}
+
+// unmarshalWithUnknown decodes data into v (a pointer to a struct) and returns
+// the object members that did not map onto any of its fields.
+func unmarshalWithUnknown(data []byte, v any) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	// encoding/json matches member names case-insensitively, so do the same here.
+	known := jsonFieldNames(reflect.TypeOf(v).Elem())
+	for name := range members {
+		if slices.ContainsFunc(known, func(k string) bool { return strings.EqualFold(k, name) }) {
+			delete(members, name)
+		}
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+	return members, nil
+}
+
+// marshalWithUnknown encodes v (a struct) and appends the unknown members to the
+// resulting object in sorted order.
+func marshalWithUnknown(v any, unknown map[string]json.RawMessage) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || len(unknown) == 0 {
+		return b, err
+	}
+	known := jsonFieldNames(reflect.TypeOf(v))
+	var buf bytes.Buffer
+	buf.Write(b[:len(b)-1])
+	for _, name := range slices.Sorted(maps.Keys(unknown)) {
+		if slices.Contains(known, name) {
+			continue
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(unknown[name])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,77 @@
+// Code generated by isbgen from spec.yaml. DO NOT EDIT.
+
+package isbclient
+
+// AccountStatus values: current status of the account.
+const (
+	AccountStatusAvailable       = "Available"
+	AccountStatusActive          = "Active"
+	AccountStatusAwaitingRecycle = "AwaitingRecycle"
+	AccountStatusQuarantined     = "Quarantined"
+)
+
+// ThresholdAction values: action to take when threshold is reached.
+const (
+	ThresholdActionAlert         = "ALERT"
+	ThresholdActionFreezeAccount = "FREEZE_ACCOUNT"
+)
+
+// LeaseStatus values: current status of the lease.
+const (
+	LeaseStatusPendingApproval = "PendingApproval"
+	LeaseStatusActive          = "Active"
+	LeaseStatusFrozen          = "Frozen"
+	LeaseStatusTerminated      = "Terminated"
+	LeaseStatusExpired         = "Expired"
+)
+
+// UnregisteredAccountStatus values: the status of the account in Organizations.
+const (
+	UnregisteredAccountStatusActive    = "ACTIVE"
+	UnregisteredAccountStatusSuspended = "SUSPENDED"
+	UnregisteredAccountStatusPending   = "PENDING"
+)
+
+// UnregisteredAccountJoinedMethod values: the method by which the account joined the organization.
+const (
+	UnregisteredAccountJoinedMethodInvited = "INVITED"
+	UnregisteredAccountJoinedMethodCreated = "CREATED"
+)
+
+// GetAccountByIDRequest is the request of GetAccountByID.
+// GET /accounts/{awsAccountId}
+type GetAccountByIDRequest struct {
+	AwsAccountID string
+}
+
+// GetAccountByIDResponse is the response of GetAccountByID.
+// GET /accounts/{awsAccountId}
+type GetAccountByIDResponse struct {
+	Account Account `json:"data"`
+}
+
+// GetLoginStatusResponse is the response of GetLoginStatus.
+// GET /auth/login/status
+type GetLoginStatusResponse struct {
+	Authenticated bool           `json:"authenticated,omitempty"`
+	Session       map[string]any `json:"session,omitempty"`
+	Message       string         `json:"message,omitempty"`
+}
+
+// CreateLeaseTemplateRequest is the request of CreateLeaseTemplate.
+// POST /leaseTemplates
+type CreateLeaseTemplateRequest struct {
+	Name                 string              `json:"name"`
+	Description          string              `json:"description"`
+	MaxSpend             float64             `json:"maxSpend,omitempty"`
+	LeaseDurationInHours int                 `json:"leaseDurationInHours,omitempty"`
+	BudgetThresholds     []BudgetThreshold   `json:"budgetThresholds,omitempty"`
+	DurationThresholds   []DurationThreshold `json:"durationThresholds,omitempty"`
+	RequiresApproval     bool                `json:"requiresApproval"`
+}
+
+// CreateLeaseTemplateResponse is the response of CreateLeaseTemplate.
+// POST /leaseTemplates
+type CreateLeaseTemplateResponse struct {
+	LeaseTemplate LeaseTemplate `json:"data"`
+}
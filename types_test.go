@@ -1,8 +1,10 @@
 package isbclient
 
 import (
+	"encoding/json"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -171,3 +173,73 @@ func TestNilReceivers_BuildQuery(t *testing.T) {
 		}
 	})
 }
+
+func TestLeaseTemplate_PreservesUnknownFields(t *testing.T) {
+	in := `{"uuid":"tpl-1","name":"Sandbox","requiresApproval":true,"createdBy":"admin@example.com","costCenter":"cc-42","tags":{"team":"data"}}`
+	var tpl LeaseTemplate
+	if err := json.Unmarshal([]byte(in), &tpl); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	unknown := tpl.UnknownFields()
+	if len(unknown) != 2 || string(unknown["costCenter"]) != `"cc-42"` || string(unknown["tags"]) != `{"team":"data"}` {
+		t.Fatalf("unexpected unknown fields: %v", unknown)
+	}
+
+	tpl.Name = "Sandbox 7d"
+	out, err := json.Marshal(NewUpdateLeaseTemplateRequest(tpl))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(out, &body); err != nil {
+		t.Fatalf("unmarshal body: %v", err)
+	}
+	if body["name"] != "Sandbox 7d" || body["costCenter"] != "cc-42" || body["requiresApproval"] != true {
+		t.Errorf("unexpected update body: %s", out)
+	}
+	if _, ok := body["LeaseTemplateID"]; ok {
+		t.Errorf("path parameter leaked into update body: %s", out)
+	}
+}
+
+func TestUnknownFields_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		v    interface {
+			UnknownFields() map[string]json.RawMessage
+		}
+	}{
+		{"Lease", `{"uuid":"l-1","status":"Active","newField":[1,2]}`, &Lease{}},
+		{"Account", `{"awsAccountId":"123456789012","status":"Available","newField":[1,2]}`, &Account{}},
+		{"GlobalConfiguration", `{"maintenanceMode":true,"newField":[1,2]}`, &GlobalConfiguration{}},
+		{"GlobalAuthConfig", `{"webAppUrl":"https://isb.example.com","newField":[1,2]}`, &GlobalAuthConfig{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := json.Unmarshal([]byte(tt.in), tt.v); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if got := string(tt.v.UnknownFields()["newField"]); got != "[1,2]" {
+				t.Errorf("expected newField to be retained, got %q", got)
+			}
+			out, err := json.Marshal(tt.v)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if !strings.Contains(string(out), `"newField":[1,2]`) {
+				t.Errorf("expected newField to be written back, got %s", out)
+			}
+		})
+	}
+}
+
+func TestUnknownFields_NoneWhenFullyModelled(t *testing.T) {
+	var acct Account
+	if err := json.Unmarshal([]byte(`{"awsAccountId":"123456789012","STATUS":"Active"}`), &acct); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if acct.Status != "Active" || acct.UnknownFields() != nil {
+		t.Errorf("expected case-insensitive match and no unknown fields, got %+v", acct)
+	}
+}
@@ -0,0 +1,362 @@
+package isbclient
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed spec.yaml
+var specYAML []byte
+
+// embeddedSpec returns spec.yaml parsed into generic maps.
+var embeddedSpec = sync.OnceValues(func() (map[string]any, error) {
+	var spec map[string]any
+	err := yaml.Unmarshal(specYAML, &spec)
+	return spec, err
+})
+
+// specDefects are violations caused by errors in the published spec rather than by the API.
+// They are never reported.
+var specDefects = []struct{ schema, keyword, member string }{
+	{"#/components/schemas/PaginatedResults", "required", "items"}, // pages are returned as result
+	{"#/components/schemas/MetaData", "type", "schemaVersion"},     // returned as a number
+}
+
+var (
+	emailFormat = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	uuidFormat  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// SchemaViolation is a difference between a response body and the schema spec.yaml declares
+// for it.
+type SchemaViolation struct {
+	Path    string // location in the body, e.g. "$.data.result[0].status"
+	Schema  string // schema declaring the constraint, e.g. "#/components/schemas/Lease"
+	Keyword string // "required", "type", "enum", "format", "minimum" or "minItems"
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// ValidationOptions configures ValidateResponses.
+type ValidationOptions struct {
+	// OnViolation receives the violations found in each response. It defaults to logging
+	// them with slog.Default at warning level.
+	OnViolation func(ctx context.Context, call *Call, violations []SchemaViolation)
+
+	// Strict fails calls whose response violates the spec with a *ResponseValidationError,
+	// after OnViolation has been called.
+	Strict bool
+}
+
+// ValidateResponses returns middleware that validates every successful response body against
+// the schema the embedded spec.yaml declares for its path, method and status. Required
+// members, types, enums, formats (date-time, email, uuid), minimum and minItems are checked.
+//
+// Responses the client could not decode are validated as well; their violations are reported
+// alongside the *JSONDecodingError the call fails with.
+//
+// Validation keeps a copy of each response body, so it is meant for staging and tests rather
+// than production traffic. Register it after Cache so that cache hits are not validated again.
+func ValidateResponses(opts ValidationOptions) Middleware {
+	if opts.OnViolation == nil {
+		opts.OnViolation = logViolations
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			if call.Method == "" {
+				return next(ctx, call)
+			}
+			call.captureBody, call.responseBody = true, nil
+			res, err := next(ctx, call)
+			// Bodies the client failed to decode are validated too, to explain the failure.
+			var decoding *JSONDecodingError
+			if (err != nil && !errors.As(err, &decoding)) || call.Response == nil || call.responseBody == nil {
+				return res, err
+			}
+			violations := validateResponse(call.Method, call.Path, call.Response.HTTPStatus, call.responseBody)
+			if len(violations) == 0 {
+				return res, err
+			}
+			opts.OnViolation(ctx, call, violations)
+			if opts.Strict {
+				return nil, &ResponseValidationError{Op: call.Operation, URL: call.Path, Violations: violations, Err: err}
+			}
+			return res, err
+		}
+	}
+}
+
+func logViolations(ctx context.Context, call *Call, violations []SchemaViolation) {
+	messages := make([]string, len(violations))
+	for i, v := range violations {
+		messages[i] = redact(v.String())
+	}
+	slog.Default().LogAttrs(ctx, slog.LevelWarn, "isb response violates spec",
+		slog.String("operation", call.Operation),
+		slog.String("path", redact(call.Path)),
+		slog.Any("violations", messages),
+	)
+}
+
+// validateResponse returns the violations of body, the response to method and path with the
+// given status. Responses the spec declares no JSON schema for are not validated.
+func validateResponse(method, path string, status int, body []byte) []SchemaViolation {
+	spec, err := embeddedSpec()
+	if err != nil {
+		return nil
+	}
+	paths, _ := spec["paths"].(map[string]any)
+	tmpl, ok := matchPathTemplate(paths, path)
+	if !ok {
+		return nil
+	}
+	op, _ := paths[tmpl].(map[string]any)[strings.ToLower(method)].(map[string]any)
+	responses, _ := op["responses"].(map[string]any)
+	resp, _ := responses[strconv.Itoa(status)].(map[string]any)
+	if resp == nil {
+		resp, _ = responses["default"].(map[string]any)
+	}
+	if ref, ok := resp["$ref"].(string); ok {
+		resp = specRef(spec, ref)
+	}
+	content, _ := resp["content"].(map[string]any)
+	media, _ := content["application/json"].(map[string]any)
+	schema, _ := media["schema"].(map[string]any)
+	if schema == nil {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return []SchemaViolation{{Path: "$", Keyword: "type", Message: "body is not valid JSON: " + err.Error()}}
+	}
+	v := &schemaValidator{spec: spec}
+	v.validate(schema, value, "$", "#/paths/"+tmpl)
+	slices.SortFunc(v.violations, func(a, b SchemaViolation) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Keyword, b.Keyword))
+	})
+	return v.violations
+}
+
+// matchPathTemplate returns the path template in paths matching path, preferring templates
+// with more literal segments ("/accounts/unregistered" over "/accounts/{awsAccountId}").
+func matchPathTemplate(paths map[string]any, path string) (string, bool) {
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	best, bestLiterals := "", -1
+	for tmpl := range paths {
+		tsegs := strings.Split(strings.Trim(tmpl, "/"), "/")
+		if len(tsegs) != len(segs) {
+			continue
+		}
+		literals := 0
+		for i, t := range tsegs {
+			switch {
+			case strings.HasPrefix(t, "{") && segs[i] != "":
+			case t == segs[i]:
+				literals++
+			default:
+				literals = -1
+			}
+			if literals < 0 {
+				break
+			}
+		}
+		if literals > bestLiterals {
+			best, bestLiterals = tmpl, literals
+		}
+	}
+	return best, bestLiterals >= 0
+}
+
+func specRef(spec map[string]any, ref string) map[string]any {
+	var node any = spec
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, _ := node.(map[string]any)
+		node = m[part]
+	}
+	m, _ := node.(map[string]any)
+	return m
+}
+
+type schemaValidator struct {
+	spec       map[string]any
+	violations []SchemaViolation
+}
+
+func (v *schemaValidator) report(path, location, keyword, format string, args ...any) {
+	member := path[strings.LastIndexAny(path, ".[")+1:]
+	for _, d := range specDefects {
+		if d.schema == location && d.keyword == keyword && d.member == member {
+			return
+		}
+	}
+	v.violations = append(v.violations, SchemaViolation{
+		Path:    path,
+		Schema:  location,
+		Keyword: keyword,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validate checks value against schema. path is the location of value in the body and
+// location that of the schema in the spec.
+func (v *schemaValidator) validate(schema map[string]any, value any, path, location string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		v.validate(specRef(v.spec, ref), value, path, ref)
+		return
+	}
+	if parts, ok := schema["allOf"].([]any); ok {
+		for _, part := range parts {
+			if p, ok := part.(map[string]any); ok {
+				v.validate(p, value, path, location)
+			}
+		}
+	}
+	typ, _ := schema["type"].(string)
+	if typ == "" && schema["properties"] != nil {
+		typ = "object"
+	}
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); typ != "" && !nullable {
+			v.report(path, location, "type", "expected %s, got null", typ)
+		}
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return enumEqual(e, value) }) {
+		v.report(path, location, "enum", "value %v is not one of %v", value, enum)
+	}
+
+	switch typ {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			v.report(path, location, "type", "expected object, got %s", jsonType(value))
+			return
+		}
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, present := obj[name]; !present {
+					v.report(path+"."+name, location, "required", "required member is missing")
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for name, prop := range props {
+			if val, ok := obj[name]; ok {
+				if p, ok := prop.(map[string]any); ok {
+					v.validate(p, val, path+"."+name, location)
+				}
+			}
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			v.report(path, location, "type", "expected array, got %s", jsonType(value))
+			return
+		}
+		if min, ok := specNumber(schema["minItems"]); ok && float64(len(arr)) < min {
+			v.report(path, location, "minItems", "expected at least %v items, got %d", min, len(arr))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range arr {
+				v.validate(items, item, fmt.Sprintf("%s[%d]", path, i), location)
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			v.report(path, location, "type", "expected string, got %s", jsonType(value))
+			return
+		}
+		if format, _ := schema["format"].(string); !validFormat(format, s) {
+			v.report(path, location, "format", "expected a %s", format)
+		}
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			v.report(path, location, "type", "expected %s, got %s", typ, jsonType(value))
+			return
+		}
+		if _, err := n.Int64(); typ == "integer" && err != nil {
+			v.report(path, location, "type", "expected integer, got %s", n)
+			return
+		}
+		f, _ := n.Float64()
+		if min, ok := specNumber(schema["minimum"]); ok && f < min {
+			v.report(path, location, "minimum", "expected at least %v, got %s", min, n)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.report(path, location, "type", "expected boolean, got %s", jsonType(value))
+		}
+	}
+}
+
+func validFormat(format, s string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "email":
+		return emailFormat.MatchString(s)
+	case "uuid":
+		return uuidFormat.MatchString(s)
+	}
+	return true
+}
+
+// enumEqual reports whether the JSON value v equals the enum member e decoded from YAML.
+func enumEqual(e, v any) bool {
+	if n, ok := v.(json.Number); ok {
+		f, _ := n.Float64()
+		ef, ok := specNumber(e)
+		return ok && ef == f
+	}
+	return e == v
+}
+
+func specNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
@@ -0,0 +1,132 @@
+package isbclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateResponse_SpecExamples(t *testing.T) {
+	spec := loadSpec(t)
+	for path, item := range spec["paths"].(map[string]any) {
+		for method, op := range item.(map[string]any) {
+			opMap, ok := op.(map[string]any)
+			if !ok || method == "parameters" {
+				continue
+			}
+			responses, ok := opMap["responses"].(map[string]any)
+			if !ok {
+				continue
+			}
+			for status, resp := range responses {
+				content, ok := resp.(map[string]any)["content"].(map[string]any)
+				if !ok || status[0] != '2' {
+					continue
+				}
+				schema := content["application/json"].(map[string]any)["schema"].(map[string]any)
+				body, err := json.Marshal(specExample(spec, schema))
+				if err != nil {
+					t.Fatalf("encode example: %v", err)
+				}
+				code := map[string]int{"200": 200, "201": 201}[status]
+				for _, v := range validateResponse(method, path, code, body) {
+					// Members required but not declared, such as GlobalConfiguration.auth, have no example.
+					member := v.Path[strings.LastIndex(v.Path, ".")+1:]
+					if props, _ := specRef(spec, v.Schema)["properties"].(map[string]any); v.Keyword == "required" && props[member] == nil {
+						continue
+					}
+					t.Errorf("%s %s: example violates the spec: %v", method, path, v)
+				}
+			}
+		}
+	}
+}
+
+func TestValidateResponses(t *testing.T) {
+	body := `{"status":"success","data":{
+		"uuid":"0f0f0f0f-1234-5678-9abc-def012345678",
+		"status":"Pending",
+		"originalLeaseTemplateUuid":"not-a-uuid",
+		"originalLeaseTemplateName":"tpl",
+		"comments":null,
+		"budgetThresholds":[{"dollarsSpent":50,"action":"EMAIL"}],
+		"startDate":"yesterday",
+		"meta":{"schemaVersion":1}
+	}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	var reported []SchemaViolation
+	var reportedOp string
+	client := NewClient(server.URL, "token")
+	client.Use(ValidateResponses(ValidationOptions{OnViolation: func(_ context.Context, call *Call, violations []SchemaViolation) {
+		reportedOp, reported = call.Operation, violations
+	}}))
+
+	resp, err := client.GetLeaseByID(context.Background(), &GetLeaseByIDRequest{LeaseID: "lease-1"})
+	if err != nil {
+		t.Fatalf("GetLeaseByID error: %v", err)
+	}
+	if resp == nil {
+		t.Fatal("expected the response to be returned outside strict mode")
+	}
+	want := []SchemaViolation{
+		{Path: "$.data.budgetThresholds[0].action", Schema: "#/components/schemas/BudgetThresholds", Keyword: "enum", Message: "value EMAIL is not one of [ALERT FREEZE_ACCOUNT]"},
+		{Path: "$.data.comments", Schema: "#/components/schemas/Comments", Keyword: "type", Message: "expected string, got null"},
+		{Path: "$.data.originalLeaseTemplateUuid", Schema: "#/components/schemas/LeaseTemplateUuid", Keyword: "format", Message: "expected a uuid"},
+		{Path: "$.data.startDate", Schema: "#/components/schemas/StartDate", Keyword: "format", Message: "expected a date-time"},
+		{Path: "$.data.status", Schema: "#/components/schemas/LeaseStatus", Keyword: "enum", Message: "value Pending is not one of [PendingApproval Active Frozen Terminated Expired]"},
+		{Path: "$.data.userEmail", Schema: "#/components/schemas/Lease", Keyword: "required", Message: "required member is missing"},
+	}
+	if reportedOp != "GetLeaseByID" || !reflect.DeepEqual(reported, want) {
+		t.Errorf("unexpected violations for %s:\n got %+v\nwant %+v", reportedOp, reported, want)
+	}
+}
+
+func TestValidateResponses_Strict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"maintenanceMode":"no"}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+	client.Use(ValidateResponses(ValidationOptions{Strict: true, OnViolation: func(context.Context, *Call, []SchemaViolation) {}}))
+
+	_, err := client.GetConfigurations(context.Background())
+	var validation *ResponseValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("expected ResponseValidationError, got %T %v", err, err)
+	}
+	if validation.Op != "GetConfigurations" || len(validation.Violations) == 0 {
+		t.Errorf("unexpected error: %+v", validation)
+	}
+	var decoding *JSONDecodingError
+	if !errors.As(err, &decoding) {
+		t.Errorf("expected the decoding error to remain available, got %v", validation.Err)
+	}
+}
+
+func TestMatchPathTemplate(t *testing.T) {
+	paths := map[string]any{"/accounts": nil, "/accounts/{awsAccountId}": nil, "/accounts/unregistered": nil, "/leases/{leaseId}/freeze": nil}
+	tests := map[string]string{
+		"/accounts":               "/accounts",
+		"/accounts/123456789012":  "/accounts/{awsAccountId}",
+		"/accounts/unregistered":  "/accounts/unregistered",
+		"/leases/abc/freeze":      "/leases/{leaseId}/freeze",
+		"/leases/abc/unknown":     "",
+		"/configurations/unknown": "",
+	}
+	for path, want := range tests {
+		if got, _ := matchPathTemplate(paths, path); got != want {
+			t.Errorf("matchPathTemplate(%q) = %q, want %q", path, got, want)
+		}
+	}
+}